@@ -3,19 +3,31 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"slices"
 	"syscall"
-	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/dzherb/mifi-go-microservice/config"
+	"github.com/dzherb/mifi-go-microservice/grpcserver"
+	"github.com/dzherb/mifi-go-microservice/health"
+	"github.com/dzherb/mifi-go-microservice/lifecycle"
 	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
 	"github.com/dzherb/mifi-go-microservice/model"
 	"github.com/dzherb/mifi-go-microservice/server"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
 	"github.com/dzherb/mifi-go-microservice/service"
+	"github.com/dzherb/mifi-go-microservice/slo"
 	"github.com/dzherb/mifi-go-microservice/storage"
+	"github.com/dzherb/mifi-go-microservice/tracing"
 )
 
 func main() {
@@ -27,110 +39,462 @@ func main() {
 
 	defer stop()
 
-	log := logger.New(envOrDefault("LOG_LEVEL", "info"))
+	// SIGHUP перечитывает конфигурацию вместо остановки процесса
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+
+	defer signal.Stop(reloadSignals)
+
+	cfg, opts, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if opts.PrintConfig {
+		err = cfg.Print(os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	// формат правил уже проверен в config.Load
+	logSampling, _ := logger.ParseSamplingRules(
+		cfg.Log.Sampling,
+		cfg.Log.SamplingInterval,
+	)
+
+	appLog, err := logger.New(logger.Config{
+		Level:    cfg.Log.Level,
+		Outputs:  logOutputs(cfg.Log),
+		Sampling: logSampling,
+		Redact: logger.RedactConfig{
+			RedactKeys: cfg.Log.RedactKeys,
+			HashKeys:   cfg.Log.HashKeys,
+			HashSalt:   cfg.Log.HashSalt,
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid logger configuration:", err)
+		os.Exit(1)
+	}
+
+	defer appLog.Close()
+
+	log := appLog.Logger
+
+	log.Info(
+		"configuration loaded",
+		slog.String("config_file", opts.File),
+	)
+
+	storageLog := logger.Named(log, "storage")
+	serviceLog := logger.Named(log, "service")
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		FilePath:     cfg.Tracing.FilePath,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		panic("tracing initialization: " + err.Error())
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	metrics := metric.New(registry)
+
+	minioCfg := storage.MiniIOConfig{
+		Endpoint: cfg.MinIO.Endpoint,
+		Credentials: storage.CredentialsConfig{
+			AccessKey:       cfg.MinIO.AccessKey,
+			SecretKey:       cfg.MinIO.SecretKey,
+			AccessKeyFile:   cfg.MinIO.AccessKeyFile,
+			SecretKeyFile:   cfg.MinIO.SecretKeyFile,
+			RefreshInterval: cfg.MinIO.CredentialsRefreshInterval,
+		},
+		BucketName:              cfg.MinIO.Bucket,
+		UseSSL:                  cfg.MinIO.UseSSL,
+		AllowDefaultCredentials: cfg.MinIO.AllowDefaultCredentials,
+	}
 
 	userStorage, err := storage.NewMiniIO[model.User](
 		ctx,
+		storageLog,
+		metrics,
+		minioCfg,
+	)
+	if err != nil {
+		panic("user storage initialization: " + err.Error())
+	}
+
+	idempotencyCfg := minioCfg
+	idempotencyCfg.BucketName = cfg.MinIO.IdempotencyBucket
+	idempotencyCfg.ExpireAfter = cfg.Idempotency.KeyTTL
+
+	idempotencyStorage, err := storage.NewMiniIO[model.IdempotencyRecord](
+		ctx,
+		storageLog,
+		metrics,
+		idempotencyCfg,
+	)
+	if err != nil {
+		panic("idempotency storage initialization: " + err.Error())
+	}
+
+	userService := service.NewUserService(
+		serviceLog,
+		metrics,
+		storage.NewInstrumented(minioCfg.BucketName, metrics, userStorage),
+	)
+	go userService.WatchUsersCount(ctx, cfg.Users.CountRefreshInterval)
+
+	notifier := service.NewNotifier(
+		logger.Named(log, "notifier"),
+		metrics,
+		service.NotifierConfig{
+			WebhookURLs: cfg.Notifier.WebhookURLs,
+			Timeout:     cfg.Notifier.Timeout,
+			QueueSize:   cfg.Notifier.QueueSize,
+			Workers:     cfg.Notifier.Workers,
+		},
+	)
+	notifier.Start()
+
+	checker := health.NewChecker(cfg.Health.ReadinessTimeout)
+	checker.Register("storage", userStorage.Ping)
+	checker.Register("notifier_queue", notifier.CheckQueue)
+
+	idempotencyService := service.NewIdempotencyService(
+		serviceLog,
+		storage.NewInstrumented(
+			idempotencyCfg.BucketName,
+			metrics,
+			idempotencyStorage,
+		),
+		cfg.Idempotency.KeyTTL,
+	)
+
+	rateLimiter := middleware.NewRateLimiter(
+		log,
+		float64(cfg.API.MaxRequestsPerSecond),
+		cfg.API.MaxBurst,
+	)
+
+	// слоты общие для HTTP и gRPC API
+	concurrencyLimiter := middleware.NewConcurrencyLimiter(
+		log,
+		metrics,
+		middleware.ConcurrencyLimitConfig{
+			MaxInFlight:      cfg.API.MaxConcurrentRequests,
+			ReservedForReads: cfg.API.ReservedReadSlots,
+			QueueTimeout:     cfg.API.ConcurrencyQueueTimeout,
+			RetryAfter:       cfg.API.ShedRetryAfter,
+		},
+	)
+
+	// учитывает запросы, чтобы при остановке дождаться их
+	// до закрытия хранилища
+	inFlight := middleware.NewInFlightTracker()
+
+	apiHandler, err := server.RootHandler(
 		log,
-		storage.MiniIOConfig{
-			Endpoint:   envOrDefault("MINIO_ENDPOINT", "localhost:9000"),
-			AccessKey:  envOrDefault("MINIO_ACCESS_KEY", "minioadmin"),
-			SecretKey:  envOrDefault("MINIO_SECRET_KEY", "minioadmin"),
-			BucketName: envOrDefault("MINIO_BUCKET", "users"),
-			UseSSL:     envOrDefault("MINIO_USE_SSL", "false") == "true",
+		metrics,
+		userService,
+		notifier,
+		idempotencyService,
+		rateLimiter,
+		concurrencyLimiter,
+		&server.APIConfig{
+			MaxBodyBytes:               cfg.API.MaxBodyBytes,
+			AccessLogSuccessSampleRate: cfg.API.AccessLogSuccessSampleRate,
+			ValidateResponses:          cfg.API.ValidateResponses,
 		},
 	)
 	if err != nil {
-		panic("user storage initialization: " + err.Error())
+		panic("api handler initialization: " + err.Error())
 	}
 
-	userService := service.NewUserService(log, userStorage)
-	notifier := service.NewNotifier(log)
+	// gRPC сервер использует тот же сертификат, что и HTTP API
+	apiTLS := server.TLSConfig{
+		CertFile:     cfg.Server.TLS.CertFile,
+		KeyFile:      cfg.Server.TLS.KeyFile,
+		MinVersion:   cfg.Server.TLS.MinVersion,
+		CipherSuites: cfg.Server.TLS.CipherSuites,
+		ClientCAFile: cfg.Server.TLS.ClientCAFile,
+		ClientAuth:   cfg.Server.TLS.ClientAuth,
+	}
 
-	srv := server.New(
-		server.RootHandler(
-			log,
+	srv, err := server.New(
+		log,
+		inFlight.Middleware(apiHandler),
+		server.Config{
+			Host:              cfg.Server.Host,
+			Port:              cfg.Server.Port,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+			TLS:               apiTLS,
+		},
+	)
+	if err != nil {
+		panic("api server initialization: " + err.Error())
+	}
+
+	var grpcSrv *grpcserver.Server
+
+	if cfg.GRPC.Enabled {
+		grpcSrv, err = grpcserver.New(
+			logger.Named(log, "grpc"),
+			metrics,
 			userService,
 			notifier,
-			&server.APIConfig{
-				MaxRequestsPerSecond: intEnvOrDefault(
-					"API_MAX_REQUESTS_PER_SECOND",
-					1000,
-				),
-				MaxBurst: intEnvOrDefault("API_MAX_BURST", 1000),
+			rateLimiter,
+			concurrencyLimiter,
+			grpcserver.Config{
+				Host: cfg.GRPC.Host,
+				Port: cfg.GRPC.Port,
+				TLS:  apiTLS,
 			},
+		)
+		if err != nil {
+			panic("grpc server initialization: " + err.Error())
+		}
+	}
+
+	// формат целей уже проверен в config.Load
+	sloObjectives, _ := slo.ParseObjectives(cfg.SLO.Objectives)
+
+	sloTracker := slo.NewTracker(
+		logger.Named(log, "slo"),
+		registry,
+		sloObjectives,
+		cfg.SLO.Windows,
+		cfg.SLO.SampleInterval,
+	)
+	registry.MustRegister(sloTracker)
+
+	go sloTracker.Run(ctx)
+
+	adminSrv, err := server.New(
+		log,
+		server.AdminHandler(
+			log,
+			registry,
+			checker,
+			sloTracker,
+			appLog.Levels,
 		),
 		server.Config{
-			Port: intEnvOrDefault("SERVER_PORT", 8080),
-			ReadHeaderTimeout: time.Duration(
-				intEnvOrDefault("SERVER_READ_HEADER_TIMEOUT_IN_MS", 10000),
-			) * time.Millisecond,
+			Host: cfg.Admin.Host,
+			Port: cfg.Admin.Port,
+			// без WriteTimeout: /debug/pprof/profile и trace пишут
+			// ответ столько секунд, сколько попросил клиент
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 		},
 	)
+	if err != nil {
+		panic("admin server initialization: " + err.Error())
+	}
 
-	serverDone := make(chan struct{})
-
-	go func() {
-		log.Info(
-			"starting api server",
-			slog.String("address", srv.Addr),
+	reloader := config.NewReloader(
+		logger.Named(log, "config"),
+		cfg,
+		os.Args[1:],
+		os.LookupEnv,
+	)
+	reloader.OnReload(func(cfg config.Config, changed []string) error {
+		rateLimiter.SetLimit(
+			float64(cfg.API.MaxRequestsPerSecond),
+			cfg.API.MaxBurst,
 		)
+		notifier.SetTargets(cfg.Notifier.WebhookURLs)
 
-		err := srv.ListenAndServe()
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error(
-				"server unexpectedly stopped",
-				slog.String("error", err.Error()),
-			)
+		// уровень, измененный через PUT /admin/loglevel, сохраняется,
+		// пока в конфигурации не поменяется сам log.level
+		if !slices.Contains(changed, "log.level") {
+			return nil
+		}
+
+		lvl, err := logger.ParseLevel(cfg.Log.Level)
+		if err != nil {
+			return err
 		}
 
-		serverDone <- struct{}{}
-	}()
+		appLog.Levels.SetLevel(lvl)
+
+		return nil
+	})
 
-	select {
-	case <-ctx.Done():
-	case <-serverDone:
+	go reloader.Run(ctx, reloadSignals, opts.File, cfg.Reload.WatchInterval)
+
+	servers := map[string]*http.Server{
+		"api":   srv,
+		"admin": adminSrv,
 	}
 
-	log.Info("shutting down")
+	// буфер на все серверы, чтобы горутины не зависли,
+	// если после остановки их уже никто не ждет
+	serverDone := make(chan struct{}, len(servers)+1)
 
-	shutdownCtx, cancel := context.WithTimeout(
-		context.Background(),
-		10*time.Second,
+	for name, s := range servers {
+		go func() {
+			log.Info(
+				"starting "+name+" server",
+				slog.String("address", s.Addr),
+			)
+
+			var err error
+			if s.TLSConfig != nil {
+				err = s.ListenAndServeTLS("", "")
+			} else {
+				err = s.ListenAndServe()
+			}
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error(
+					name+" server unexpectedly stopped",
+					slog.String("error", err.Error()),
+				)
+			}
+
+			serverDone <- struct{}{}
+		}()
+	}
+
+	if grpcSrv != nil {
+		go func() {
+			log.Info(
+				"starting grpc server",
+				slog.String("address", grpcSrv.Addr()),
+			)
+
+			err := grpcSrv.ListenAndServe()
+			if err != nil {
+				log.Error(
+					"grpc server unexpectedly stopped",
+					slog.String("error", err.Error()),
+				)
+			}
+
+			serverDone <- struct{}{}
+		}()
+	}
+
+	shutdown := lifecycle.New(logger.Named(log, "lifecycle"))
+
+	shutdown.AddPhase("not_ready", 0, func(context.Context) error {
+		checker.MarkShuttingDown()
+
+		if grpcSrv != nil {
+			grpcSrv.MarkNotServing()
+		}
+
+		return nil
+	})
+	shutdown.AddPhase(
+		"drain_delay",
+		0,
+		func(ctx context.Context) error {
+			// сервер уже упал, и ждать, пока балансировщик
+			// заметит неготовность, незачем
+			if lifecycle.Stopped(ctx) {
+				return nil
+			}
+
+			return lifecycle.Delay(cfg.Shutdown.DrainDelay)(ctx)
+		},
+	)
+	shutdown.AddPhase(
+		"http_shutdown",
+		cfg.Shutdown.HTTPTimeout,
+		lifecycle.ShutdownServers(servers),
+	)
+
+	if grpcSrv != nil {
+		shutdown.AddPhase(
+			"grpc_shutdown",
+			cfg.Shutdown.GRPCTimeout,
+			grpcSrv.Shutdown,
+		)
+	}
+
+	shutdown.AddPhase(
+		"wait_handlers",
+		cfg.Shutdown.HandlersTimeout,
+		inFlight.Wait,
+	)
+	shutdown.AddPhase(
+		"notifier_flush",
+		cfg.Shutdown.NotifierTimeout,
+		notifier.Close,
+	)
+	shutdown.AddPhase(
+		"storage_close",
+		cfg.Shutdown.StorageTimeout,
+		func(ctx context.Context) error {
+			return errors.Join(
+				userStorage.Close(ctx),
+				idempotencyStorage.Close(ctx),
+			)
+		},
+	)
+	shutdown.AddPhase(
+		"tracing_shutdown",
+		cfg.Shutdown.TracingTimeout,
+		lifecycle.PhaseFunc(shutdownTracing),
 	)
-	defer cancel()
 
-	err = srv.Shutdown(shutdownCtx)
+	// остановка любого из серверов останавливает и остальные
+	err = shutdown.Run(ctx, serverDone)
 	if err != nil {
 		log.Error(
-			"server shutdown error",
+			"shutdown finished with errors",
 			slog.String("error", err.Error()),
 		)
 	}
-
-	select {
-	case <-shutdownCtx.Done():
-	case <-serverDone:
-	}
 }
 
-func envOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func logOutputs(cfg config.LogConfig) []logger.OutputConfig {
+	outputs := []logger.OutputConfig{
+		{
+			Path:   logger.OutputStdout,
+			Format: cfg.Format,
+			Level:  cfg.StdoutLevel,
+		},
 	}
 
-	return defaultValue
-}
-
-func intEnvOrDefault(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		result, err := strconv.Atoi(value)
-		if err != nil {
-			return defaultValue
-		}
-
-		return result
+	if cfg.File.Path != "" {
+		outputs = append(outputs, logger.OutputConfig{
+			Path:       cfg.File.Path,
+			Format:     cfg.File.Format,
+			Level:      cfg.File.Level,
+			MaxSizeMB:  cfg.File.MaxSizeMB,
+			MaxBackups: cfg.File.MaxBackups,
+			MaxAgeDays: cfg.File.MaxAgeDays,
+			Compress:   cfg.File.Compress,
+		})
 	}
 
-	return defaultValue
+	return outputs
 }
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/slo"
+	"github.com/dzherb/mifi-go-microservice/tracing"
+)
+
+// Config - настройки сервиса. Значение поля берется из флага
+// командной строки, затем из переменной окружения из тега env,
+// затем из файла конфигурации по пути из тега yaml и, наконец,
+// из Default.
+//
+// Длительности задаются строкой вроде 250ms или 1m30s. Целое число
+// без единиц в файле, окружении и флагах считается миллисекундами,
+// поэтому старые переменные с суффиксом _IN_MS продолжают работать.
+// Поля с тегом secret маскируются при выводе конфигурации.
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Admin       AdminConfig       `yaml:"admin"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	API         APIConfig         `yaml:"api"`
+	MinIO       MinIOConfig       `yaml:"minio"`
+	Users       UsersConfig       `yaml:"users"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Notifier    NotifierConfig    `yaml:"notifier"`
+	Health      HealthConfig      `yaml:"health"`
+	SLO         SLOConfig         `yaml:"slo"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Reload      ReloadConfig      `yaml:"reload"`
+	Shutdown    ShutdownConfig    `yaml:"shutdown"`
+}
+
+type ServerConfig struct {
+	Host              string        `yaml:"host" env:"SERVER_HOST"`
+	Port              int           `yaml:"port" env:"SERVER_PORT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT_IN_MS"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT_IN_MS"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT_IN_MS"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT_IN_MS"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	TLS               TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig - TLS для API сервера, см. server.TLSConfig;
+// пустые пути к сертификату и ключу оставляют HTTP
+type TLSConfig struct {
+	CertFile     string   `yaml:"cert_file" env:"SERVER_TLS_CERT_FILE"`
+	KeyFile      string   `yaml:"key_file" env:"SERVER_TLS_KEY_FILE"`
+	MinVersion   string   `yaml:"min_version" env:"SERVER_TLS_MIN_VERSION"`
+	CipherSuites []string `yaml:"cipher_suites" env:"SERVER_TLS_CIPHER_SUITES"`
+	ClientCAFile string   `yaml:"client_ca_file" env:"SERVER_TLS_CLIENT_CA_FILE"`
+	ClientAuth   string   `yaml:"client_auth" env:"SERVER_TLS_CLIENT_AUTH"`
+}
+
+type AdminConfig struct {
+	Host string `yaml:"host" env:"ADMIN_HOST"`
+	Port int    `yaml:"port" env:"ADMIN_PORT"`
+}
+
+// GRPCConfig - gRPC API на отдельном порту; TLS берется
+// из server.tls
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled" env:"GRPC_ENABLED"`
+	Host    string `yaml:"host" env:"GRPC_HOST"`
+	Port    int    `yaml:"port" env:"GRPC_PORT"`
+}
+
+type APIConfig struct {
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"API_MAX_BODY_BYTES"`
+
+	MaxRequestsPerSecond int `yaml:"max_requests_per_second" env:"API_MAX_REQUESTS_PER_SECOND" reload:"true"`
+	MaxBurst             int `yaml:"max_burst" env:"API_MAX_BURST" reload:"true"`
+
+	MaxConcurrentRequests   int           `yaml:"max_concurrent_requests" env:"API_MAX_CONCURRENT_REQUESTS"`
+	ReservedReadSlots       int           `yaml:"reserved_read_slots" env:"API_RESERVED_READ_SLOTS"`
+	ConcurrencyQueueTimeout time.Duration `yaml:"concurrency_queue_timeout" env:"API_CONCURRENCY_QUEUE_TIMEOUT_IN_MS"`
+	ShedRetryAfter          time.Duration `yaml:"shed_retry_after" env:"API_SHED_RETRY_AFTER_IN_MS"`
+
+	AccessLogSuccessSampleRate float64 `yaml:"access_log_success_sample_rate" env:"ACCESS_LOG_SUCCESS_SAMPLE_RATE"`
+
+	// ValidateResponses сверяет ответы с документом OpenAPI,
+	// для разработки
+	ValidateResponses bool `yaml:"validate_responses" env:"API_VALIDATE_RESPONSES"`
+}
+
+type MinIOConfig struct {
+	Endpoint string `yaml:"endpoint" env:"MINIO_ENDPOINT"`
+	// AccessKey и SecretKey можно не задавать: тогда ключи ищутся
+	// в файлах, окружении, ~/.aws/credentials и IAM, см. storage
+	AccessKey string `yaml:"access_key" env:"MINIO_ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" env:"MINIO_SECRET_KEY" secret:"true"`
+	// AccessKeyFile и SecretKeyFile перечитываются раз в
+	// CredentialsRefreshInterval, что позволяет ротировать ключи
+	AccessKeyFile              string        `yaml:"access_key_file" env:"MINIO_ACCESS_KEY_FILE"`
+	SecretKeyFile              string        `yaml:"secret_key_file" env:"MINIO_SECRET_KEY_FILE"`
+	CredentialsRefreshInterval time.Duration `yaml:"credentials_refresh_interval" env:"MINIO_CREDENTIALS_REFRESH_INTERVAL_IN_MS"`
+	// AllowDefaultCredentials разрешает ключи minioadmin, которые
+	// годятся только для локальной разработки
+	AllowDefaultCredentials bool `yaml:"allow_default_credentials" env:"MINIO_ALLOW_DEFAULT_CREDENTIALS"`
+
+	Bucket            string `yaml:"bucket" env:"MINIO_BUCKET"`
+	IdempotencyBucket string `yaml:"idempotency_bucket" env:"MINIO_IDEMPOTENCY_BUCKET"`
+	UseSSL            bool   `yaml:"use_ssl" env:"MINIO_USE_SSL"`
+}
+
+type UsersConfig struct {
+	CountRefreshInterval time.Duration `yaml:"count_refresh_interval" env:"USERS_COUNT_REFRESH_INTERVAL_IN_MS"`
+}
+
+type IdempotencyConfig struct {
+	KeyTTL time.Duration `yaml:"key_ttl" env:"IDEMPOTENCY_KEY_TTL_IN_MS"`
+}
+
+type NotifierConfig struct {
+	WebhookURLs []string      `yaml:"webhook_urls" env:"NOTIFIER_WEBHOOK_URLS" reload:"true"`
+	Timeout     time.Duration `yaml:"timeout" env:"NOTIFIER_TIMEOUT_IN_MS"`
+	QueueSize   int           `yaml:"queue_size" env:"NOTIFIER_QUEUE_SIZE"`
+	Workers     int           `yaml:"workers" env:"NOTIFIER_WORKERS"`
+}
+
+type HealthConfig struct {
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" env:"READINESS_CHECK_TIMEOUT_IN_MS"`
+}
+
+type SLOConfig struct {
+	// Objectives - цели в формате slo.ParseObjectives
+	Objectives     string          `yaml:"objectives" env:"SLO_OBJECTIVES"`
+	Windows        []time.Duration `yaml:"windows" env:"SLO_WINDOWS"`
+	SampleInterval time.Duration   `yaml:"sample_interval" env:"SLO_SAMPLE_INTERVAL_IN_MS"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	// Format и StdoutLevel относятся к выводу в stdout
+	Format      string        `yaml:"format" env:"LOG_FORMAT"`
+	StdoutLevel string        `yaml:"stdout_level" env:"LOG_STDOUT_LEVEL"`
+	File        LogFileConfig `yaml:"file"`
+
+	// Sampling - правила в формате logger.ParseSamplingRules
+	Sampling         string        `yaml:"sampling" env:"LOG_SAMPLING"`
+	SamplingInterval time.Duration `yaml:"sampling_interval" env:"LOG_SAMPLING_INTERVAL_IN_MS"`
+
+	RedactKeys []string `yaml:"redact_keys" env:"LOG_REDACT_KEYS"`
+	HashKeys   []string `yaml:"hash_keys" env:"LOG_HASH_KEYS"`
+	HashSalt   string   `yaml:"hash_salt" env:"LOG_HASH_SALT" secret:"true"`
+}
+
+type LogFileConfig struct {
+	// Path - пустой путь отключает запись в файл
+	Path       string `yaml:"path" env:"LOG_FILE"`
+	Format     string `yaml:"format" env:"LOG_FILE_FORMAT"`
+	Level      string `yaml:"level" env:"LOG_FILE_LEVEL"`
+	MaxSizeMB  int    `yaml:"max_size_mb" env:"LOG_FILE_MAX_SIZE_MB"`
+	MaxBackups int    `yaml:"max_backups" env:"LOG_FILE_MAX_BACKUPS"`
+	MaxAgeDays int    `yaml:"max_age_days" env:"LOG_FILE_MAX_AGE_DAYS"`
+	Compress   bool   `yaml:"compress" env:"LOG_FILE_COMPRESS"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName  string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	FilePath     string  `yaml:"file_path" env:"OTEL_TRACES_FILE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+type ReloadConfig struct {
+	// WatchInterval - как часто проверять файл конфигурации
+	// на изменения, 0 отключает проверку
+	WatchInterval time.Duration `yaml:"watch_interval" env:"CONFIG_WATCH_INTERVAL_IN_MS"`
+}
+
+// ShutdownConfig - таймауты фаз остановки, см. lifecycle.Manager
+type ShutdownConfig struct {
+	// DrainDelay - сколько ждать после снятия готовности, прежде
+	// чем перестать принимать соединения
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY_IN_MS"`
+	HTTPTimeout     time.Duration `yaml:"http_timeout" env:"SHUTDOWN_HTTP_TIMEOUT_IN_MS"`
+	GRPCTimeout     time.Duration `yaml:"grpc_timeout" env:"SHUTDOWN_GRPC_TIMEOUT_IN_MS"`
+	HandlersTimeout time.Duration `yaml:"handlers_timeout" env:"SHUTDOWN_HANDLERS_TIMEOUT_IN_MS"`
+	NotifierTimeout time.Duration `yaml:"notifier_timeout" env:"SHUTDOWN_NOTIFIER_TIMEOUT_IN_MS"`
+	StorageTimeout  time.Duration `yaml:"storage_timeout" env:"SHUTDOWN_STORAGE_TIMEOUT_IN_MS"`
+	TracingTimeout  time.Duration `yaml:"tracing_timeout" env:"SHUTDOWN_TRACING_TIMEOUT_IN_MS"`
+}
+
+const defaultSLOObjectives = "" +
+	"get_user=GET /api/users/{id}:0.999:250ms:0.99;" +
+	"list_users=GET /api/users:0.999:1s:0.99;" +
+	"create_user=POST /api/users:0.999:500ms:0.99;" +
+	"update_user=PUT /api/users/{id}:0.999:500ms:0.99;" +
+	"delete_user=DELETE /api/users/{id}:0.999:500ms:0.99"
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    64 << 10,
+			TLS: TLSConfig{
+				MinVersion: "1.2",
+			},
+		},
+		Admin: AdminConfig{
+			Host: "localhost",
+			Port: 9090,
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Port:    50051,
+		},
+		API: APIConfig{
+			MaxBodyBytes:               1 << 20,
+			MaxRequestsPerSecond:       1000,
+			MaxBurst:                   1000,
+			MaxConcurrentRequests:      256,
+			ReservedReadSlots:          32,
+			ConcurrencyQueueTimeout:    200 * time.Millisecond,
+			ShedRetryAfter:             time.Second,
+			AccessLogSuccessSampleRate: 1,
+		},
+		MinIO: MinIOConfig{
+			Endpoint:                   "localhost:9000",
+			CredentialsRefreshInterval: time.Minute,
+			Bucket:                     "users",
+			IdempotencyBucket:          "idempotency-keys",
+		},
+		Users: UsersConfig{
+			CountRefreshInterval: 30 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL: 24 * time.Hour,
+		},
+		Notifier: NotifierConfig{
+			Timeout:   5 * time.Second,
+			QueueSize: 1000,
+			Workers:   4,
+		},
+		Health: HealthConfig{
+			ReadinessTimeout: 2 * time.Second,
+		},
+		SLO: SLOConfig{
+			Objectives:     defaultSLOObjectives,
+			Windows:        slo.DefaultWindows,
+			SampleInterval: 15 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logger.FormatJSON,
+			File: LogFileConfig{
+				Format:     logger.FormatJSON,
+				MaxSizeMB:  100,
+				MaxBackups: 5,
+				MaxAgeDays: 14,
+			},
+			SamplingInterval: time.Second,
+			RedactKeys:       logger.DefaultRedactKeys,
+			HashKeys:         logger.DefaultHashKeys,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			ServiceName: "mifi-go-microservice",
+			FilePath:    "traces.jsonl",
+			SampleRatio: 1,
+		},
+		Reload: ReloadConfig{
+			WatchInterval: 5 * time.Second,
+		},
+		Shutdown: ShutdownConfig{
+			DrainDelay:      5 * time.Second,
+			HTTPTimeout:     10 * time.Second,
+			GRPCTimeout:     10 * time.Second,
+			HandlersTimeout: 5 * time.Second,
+			NotifierTimeout: 5 * time.Second,
+			StorageTimeout:  2 * time.Second,
+			TracingTimeout:  5 * time.Second,
+		},
+	}
+}
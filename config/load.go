@@ -0,0 +1,387 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv - переменная окружения с путем к файлу конфигурации,
+// если он не передан флагом -config
+const FileEnv = "CONFIG_FILE"
+
+// LookupEnvFunc совпадает по сигнатуре с os.LookupEnv
+type LookupEnvFunc func(key string) (string, bool)
+
+// Options - параметры запуска, которые не входят в Config
+type Options struct {
+	// File - путь к прочитанному файлу конфигурации, пустой,
+	// если файл не использовался
+	File string
+	// PrintConfig - вывести итоговую конфигурацию и завершиться
+	PrintConfig bool
+}
+
+// Load собирает конфигурацию из значений по умолчанию, файла,
+// переменных окружения и флагов args и проверяет результат.
+// Каждое поле доступно как флаг с именем из пути тегов yaml,
+// например -server.port или -log.file.path.
+func Load(args []string, lookupEnv LookupEnvFunc) (Config, Options, error) {
+	cfg := Default()
+
+	var opts Options
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", "", "path to the YAML config file")
+	fs.BoolVar(
+		&opts.PrintConfig,
+		"print-config",
+		false,
+		"print the effective config with secrets masked and exit",
+	)
+
+	// флаги применяются после файла и окружения, поэтому
+	// здесь значения только запоминаются
+	flagValues := make(map[string]string)
+
+	for _, f := range fields(&cfg) {
+		usage := "overrides " + f.path
+		if f.env != "" {
+			usage += " and env " + f.env
+		}
+
+		setFlag := func(value string) error {
+			flagValues[f.path] = value
+
+			return nil
+		}
+
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(f.path, usage, setFlag)
+		} else {
+			fs.Func(f.path, usage, setFlag)
+		}
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, opts, fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if opts.File == "" {
+		opts.File, _ = lookupEnv(FileEnv)
+	}
+
+	if opts.File != "" {
+		err = loadFile(opts.File, &cfg)
+		if err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	var errs []error
+
+	envFields := make(map[string]bool)
+
+	for _, f := range fields(&cfg) {
+		envFields[f.env] = true
+	}
+
+	for _, f := range fields(&cfg) {
+		if f.env == "" {
+			continue
+		}
+
+		value, ok := lookupEnv(f.env)
+		if !ok || value == "" {
+			// для секретов, у которых нет отдельного поля с путем,
+			// значение можно передать файлом через env_FILE
+			if !f.secret || envFields[f.env+fileEnvSuffix] {
+				continue
+			}
+
+			value, err = secretFromFile(f.env, lookupEnv)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			if value == "" {
+				continue
+			}
+		}
+
+		err = setValue(f.value, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+		}
+	}
+
+	for _, f := range fields(&cfg) {
+		value, ok := flagValues[f.path]
+		if !ok {
+			continue
+		}
+
+		err = setValue(f.value, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", f.path, err))
+		}
+	}
+
+	errs = append(errs, cfg.Validate())
+
+	return cfg, opts, errors.Join(errs...)
+}
+
+const fileEnvSuffix = "_FILE"
+
+// secretFromFile читает значение секрета из файла, путь к которому
+// лежит в переменной env_FILE, как принято в Docker и Kubernetes
+func secretFromFile(env string, lookupEnv LookupEnvFunc) (string, error) {
+	path, ok := lookupEnv(env + fileEnvSuffix)
+	if !ok || path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("env %s%s: %w", env, fileEnvSuffix, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var root yaml.Node
+
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	// yaml не читает целое число в поле time.Duration, а окружение
+	// и флаги считают его миллисекундами; файл приводится к тем же
+	// единицам до разбора
+	err = prepareNode(root.Content[0], reflect.TypeFor[Config]())
+	if err == nil {
+		err = root.Content[0].Decode(cfg)
+	}
+
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// prepareNode обходит узлы файла вместе с полями типа t, отклоняет
+// неизвестные ключи и дописывает единицы ms к длительностям,
+// записанным целым числом
+func prepareNode(node *yaml.Node, t reflect.Type) error {
+	switch {
+	case t == durationType:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			return nil
+		}
+
+		_, err := strconv.ParseInt(node.Value, 10, 64)
+		if err == nil {
+			node.Tag = "!!str"
+			node.Value += "ms"
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		var errs []error
+
+		for _, item := range node.Content {
+			errs = append(errs, prepareNode(item, t.Elem()))
+		}
+
+		return errors.Join(errs...)
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		var errs []error
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+
+			f, ok := fieldByYAMLName(t, key.Value)
+			if !ok {
+				errs = append(errs, fmt.Errorf(
+					"line %d: field %s not found in type %s",
+					key.Line, key.Value, t,
+				))
+
+				continue
+			}
+
+			errs = append(errs, prepareNode(node.Content[i+1], f.Type))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+func fieldByYAMLName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name {
+			return t.Field(i), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+type field struct {
+	// path - имена из тегов yaml через точку
+	path   string
+	env    string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// fields обходит вложенные структуры и возвращает конечные поля
+func fields(cfg *Config) []field {
+	var result []field
+
+	var walk func(v reflect.Value, prefix string)
+
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+
+		for i := range t.NumField() {
+			sf := t.Field(i)
+
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+
+			fv := v.Field(i)
+
+			if fv.Kind() == reflect.Struct {
+				walk(fv, path)
+
+				continue
+			}
+
+			result = append(result, field{
+				path:   path,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret") == "true",
+				reload: sf.Tag.Get("reload") == "true",
+				value:  fv,
+			})
+		}
+	}
+
+	walk(reflect.ValueOf(cfg).Elem(), "")
+
+	return result
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// setValue разбирает строку из окружения или флага; списки
+// задаются через запятую
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", raw)
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		slice := reflect.MakeSlice(v.Type(), 0, len(parts))
+
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			elem := reflect.New(v.Type().Elem()).Elem()
+
+			err := setValue(elem, part)
+			if err != nil {
+				return err
+			}
+
+			slice = reflect.Append(slice, elem)
+		}
+
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported config field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// parseDuration принимает как 1m30s, так и число миллисекунд
+func parseDuration(raw string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(raw, 10, 64)
+	if err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+
+	return d, nil
+}
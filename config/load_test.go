@@ -0,0 +1,226 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envMap(env map[string]string) LookupEnvFunc {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  port: 8081
+api:
+  max_burst: 10
+log:
+  level: debug
+  format: text
+`)
+
+	cfg, opts, err := Load(
+		[]string{"-config", file, "-server.port=8083"},
+		envMap(map[string]string{
+			"SERVER_PORT": "8082",
+			"LOG_LEVEL":   "warn",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if opts.File != file {
+		t.Errorf("opts.File = %q, want %q", opts.File, file)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env and file", cfg.Server.Port, 8083},
+		{"env over file", cfg.Log.Level, "warn"},
+		{"file over default", cfg.API.MaxBurst, 10},
+		{"file over default", cfg.Log.Format, "text"},
+		{
+			"default",
+			cfg.API.MaxRequestsPerSecond,
+			Default().API.MaxRequestsPerSecond,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	file := writeFile(t, "config.yaml", "server:\n  port: 8081\n")
+
+	cfg, opts, err := Load(nil, envMap(map[string]string{FileEnv: file}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if opts.File != file || cfg.Server.Port != 8081 {
+		t.Fatalf(
+			"file %q, port %d, want %q and 8081",
+			opts.File, cfg.Server.Port, file,
+		)
+	}
+}
+
+func TestLoadDurationsUseMillisecondsEverywhere(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+shutdown:
+  drain_delay: 5000
+  http_timeout: 2s
+slo:
+  windows: [60000, 1h]
+`)
+
+	cfg, _, err := Load(
+		[]string{"-config", file, "-shutdown.handlers_timeout=1500"},
+		envMap(map[string]string{"SHUTDOWN_GRPC_TIMEOUT_IN_MS": "3000"}),
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  time.Duration
+		want time.Duration
+	}{
+		{"file integer", cfg.Shutdown.DrainDelay, 5 * time.Second},
+		{"file string", cfg.Shutdown.HTTPTimeout, 2 * time.Second},
+		{"file list integer", cfg.SLO.Windows[0], time.Minute},
+		{"file list string", cfg.SLO.Windows[1], time.Hour},
+		{"env integer", cfg.Shutdown.GRPCTimeout, 3 * time.Second},
+		{
+			"flag integer",
+			cfg.Shutdown.HandlersTimeout,
+			1500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	salt := writeFile(t, "salt", "  pepper\n")
+
+	cfg, _, err := Load(nil, envMap(map[string]string{
+		"LOG_HASH_SALT_FILE": salt,
+		// у ключей MinIO есть свои поля с путями, поэтому
+		// переменная с _FILE задает путь, а не значение
+		"MINIO_ACCESS_KEY_FILE": "/run/secrets/access",
+		"MINIO_SECRET_KEY_FILE": "/run/secrets/secret",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Log.HashSalt != "pepper" {
+		t.Errorf("log.hash_salt = %q, want pepper", cfg.Log.HashSalt)
+	}
+
+	if cfg.MinIO.AccessKeyFile != "/run/secrets/access" ||
+		cfg.MinIO.AccessKey != "" {
+		t.Errorf(
+			"minio access key %q, file %q",
+			cfg.MinIO.AccessKey, cfg.MinIO.AccessKeyFile,
+		)
+	}
+
+	// явное значение важнее файла
+	cfg, _, err = Load(nil, envMap(map[string]string{
+		"LOG_HASH_SALT":      "explicit",
+		"LOG_HASH_SALT_FILE": salt,
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Log.HashSalt != "explicit" {
+		t.Errorf("log.hash_salt = %q, want explicit", cfg.Log.HashSalt)
+	}
+
+	_, _, err = Load(nil, envMap(map[string]string{
+		"LOG_HASH_SALT_FILE": filepath.Join(t.TempDir(), "missing"),
+	}))
+	if err == nil || !strings.Contains(err.Error(), "LOG_HASH_SALT_FILE") {
+		t.Fatalf("Load() with missing secret file error = %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	file := writeFile(t, "config.yaml", "server:\n  prot: 8081\n")
+
+	_, _, err := Load([]string{"-config", file}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "field prot not found") {
+		t.Fatalf("Load() error = %v, want unknown field error", err)
+	}
+}
+
+func TestLoadJoinsAllErrors(t *testing.T) {
+	_, _, err := Load(
+		[]string{"-api.max_burst=many"},
+		envMap(map[string]string{
+			"SERVER_PORT":                "0",
+			"NOTIFIER_WORKERS":           "-1",
+			"LOG_LEVEL":                  "loud",
+			"API_SHED_RETRY_AFTER_IN_MS": "soon",
+		}),
+	)
+	if err == nil {
+		t.Fatal("Load() error = nil, want errors")
+	}
+
+	for _, want := range []string{
+		"flag -api.max_burst",
+		"env API_SHED_RETRY_AFTER_IN_MS",
+		"server.port",
+		"notifier.workers",
+		"log.level",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	err := Default().Validate()
+	if err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "******"
+
+// Masked возвращает копию конфигурации, в которой непустые
+// значения полей с тегом secret заменены маской
+func (c Config) Masked() Config {
+	for _, f := range fields(&c) {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString(secretMask)
+		}
+	}
+
+	return c
+}
+
+// Print выводит итоговую конфигурацию в YAML со скрытыми секретами
+// в том же виде, в каком ее читает Load
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(c.Masked())
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+
+	return encoder.Close()
+}
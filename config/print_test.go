@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintMasksSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.MinIO.AccessKey = "AKIA"
+	cfg.Log.HashSalt = "pepper"
+
+	var buf bytes.Buffer
+
+	err := cfg.Print(&buf)
+	if err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, raw := range []string{"AKIA", "pepper"} {
+		if strings.Contains(out, raw) {
+			t.Fatalf("printed config leaks %q:\n%s", raw, out)
+		}
+	}
+
+	for _, want := range []string{
+		"access_key: '" + secretMask + "'",
+		"hash_salt: '" + secretMask + "'",
+		// пустой секрет не маскируется, чтобы было видно, что он не задан
+		`secret_key: ""`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printed config has no %s:\n%s", want, out)
+		}
+	}
+
+	if cfg.MinIO.AccessKey != "AKIA" {
+		t.Fatal("Print must not change the config")
+	}
+}
+
+func TestPrintedConfigLoadsBack(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 8081
+	cfg.Shutdown.DrainDelay = 1500 * time.Millisecond
+	cfg.Notifier.WebhookURLs = []string{"http://hooks.local/users"}
+
+	var buf bytes.Buffer
+
+	err := cfg.Print(&buf)
+	if err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+
+	file := writeFile(t, "config.yaml", buf.String())
+
+	loaded, _, err := Load([]string{"-config", file}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var again bytes.Buffer
+
+	err = loaded.Print(&again)
+	if err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+
+	if again.String() != buf.String() {
+		t.Fatalf("loaded config:\n%s\nwant:\n%s", again.String(), buf.String())
+	}
+}
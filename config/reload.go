@@ -0,0 +1,225 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ApplyFunc применяет новую конфигурацию к работающему компоненту;
+// changed - пути измененных применимых полей, например log.level,
+// чтобы не перезаписывать значения, которые поменяли в обход файла
+type ApplyFunc func(cfg Config, changed []string) error
+
+// Reloader перечитывает конфигурацию по сигналу или при изменении
+// файла и применяет поля с тегом reload. Изменения остальных полей
+// только попадают в лог: они вступят в силу после перезапуска.
+type Reloader struct {
+	log       *slog.Logger
+	args      []string
+	lookupEnv LookupEnvFunc
+
+	mu       sync.Mutex
+	current  Config
+	appliers []ApplyFunc
+}
+
+func NewReloader(
+	log *slog.Logger,
+	current Config,
+	args []string,
+	lookupEnv LookupEnvFunc,
+) *Reloader {
+	return &Reloader{
+		log:       log,
+		args:      args,
+		lookupEnv: lookupEnv,
+		current:   current,
+	}
+}
+
+// OnReload регистрирует apply, который вызывается после каждой
+// перезагрузки с применимыми на лету изменениями
+func (r *Reloader) OnReload(apply ApplyFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appliers = append(r.appliers, apply)
+}
+
+func (r *Reloader) Current() Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload загружает конфигурацию заново; при ошибке загрузки
+// или проверки продолжает действовать прежняя
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, _, err := Load(r.args, r.lookupEnv)
+	if err != nil {
+		return err
+	}
+
+	applied, restartRequired := Changes(r.current, next)
+
+	if len(restartRequired) > 0 {
+		r.log.Warn(
+			"configuration changes require restart",
+			slog.Any("fields", restartRequired),
+		)
+	}
+
+	if len(applied) == 0 {
+		r.log.Info("configuration reloaded without runtime changes")
+
+		return nil
+	}
+
+	// неприменимые поля остаются прежними, чтобы следующая
+	// перезагрузка снова сообщила о них
+	effective := r.current
+	copyReloadable(&effective, &next)
+
+	var errs []error
+
+	for _, apply := range r.appliers {
+		errs = append(errs, apply(effective, applied))
+	}
+
+	r.current = effective
+
+	r.log.Info("configuration reloaded", slog.Any("applied", applied))
+
+	return errors.Join(errs...)
+}
+
+// Run перезагружает конфигурацию при получении значения из signals
+// и при изменении файла path, который проверяется раз в interval
+func (r *Reloader) Run(
+	ctx context.Context,
+	signals <-chan os.Signal,
+	path string,
+	interval time.Duration,
+) {
+	var fileChanged <-chan struct{}
+
+	if path != "" && interval > 0 {
+		fileChanged = WatchFile(ctx, path, interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.log.Info("reloading configuration on signal")
+		case <-fileChanged:
+			r.log.Info(
+				"reloading configuration on file change",
+				slog.String("config_file", path),
+			)
+		}
+
+		err := r.Reload()
+		if err != nil {
+			r.log.Error(
+				"failed to reload configuration",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// Changes возвращает пути измененных полей, разделенные на
+// применяемые на лету и требующие перезапуска
+func Changes(old, next Config) (reloadable, restartRequired []string) {
+	oldFields := fields(&old)
+	nextFields := fields(&next)
+
+	for i, f := range oldFields {
+		oldValue := f.value.Interface()
+		nextValue := nextFields[i].value.Interface()
+
+		if reflect.DeepEqual(oldValue, nextValue) {
+			continue
+		}
+
+		if f.reload {
+			reloadable = append(reloadable, f.path)
+		} else {
+			restartRequired = append(restartRequired, f.path)
+		}
+	}
+
+	return reloadable, restartRequired
+}
+
+func copyReloadable(dst, src *Config) {
+	dstFields := fields(dst)
+	srcFields := fields(src)
+
+	for i, f := range dstFields {
+		if f.reload {
+			f.value.Set(srcFields[i].value)
+		}
+	}
+}
+
+// WatchFile опрашивает файл раз в interval и сообщает в канал,
+// когда у него меняются время изменения или размер. Опрос вместо
+// inotify переживает замену файла через симлинк, как это делает
+// Kubernetes при обновлении ConfigMap.
+func WatchFile(
+	ctx context.Context,
+	path string,
+	interval time.Duration,
+) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last, _ := os.Stat(path)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil || !fileChanged(last, info) {
+				continue
+			}
+
+			last = info
+
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changed
+}
+
+func fileChanged(last, current os.FileInfo) bool {
+	if last == nil {
+		return true
+	}
+
+	return !last.ModTime().Equal(current.ModTime()) ||
+		last.Size() != current.Size()
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	old := Default()
+
+	next := old
+	next.API.MaxBurst = old.API.MaxBurst + 1
+	next.Log.Level = "debug"
+	next.Server.Port = old.Server.Port + 1
+
+	reloadable, restartRequired := Changes(old, next)
+
+	if want := []string{"api.max_burst", "log.level"}; !slices.Equal(
+		reloadable, want,
+	) {
+		t.Errorf("reloadable = %v, want %v", reloadable, want)
+	}
+
+	if want := []string{"server.port"}; !slices.Equal(restartRequired, want) {
+		t.Errorf("restart required = %v, want %v", restartRequired, want)
+	}
+
+	reloadable, restartRequired = Changes(old, old)
+	if len(reloadable) != 0 || len(restartRequired) != 0 {
+		t.Errorf(
+			"Changes(old, old) = %v, %v, want none",
+			reloadable, restartRequired,
+		)
+	}
+}
+
+func TestCopyReloadable(t *testing.T) {
+	dst := Default()
+
+	src := dst
+	src.API.MaxBurst = dst.API.MaxBurst + 1
+	src.Notifier.WebhookURLs = []string{"http://hooks.local/users"}
+	src.Server.Port = dst.Server.Port + 1
+
+	copyReloadable(&dst, &src)
+
+	if dst.API.MaxBurst != src.API.MaxBurst {
+		t.Errorf(
+			"api.max_burst = %d, want %d",
+			dst.API.MaxBurst, src.API.MaxBurst,
+		)
+	}
+
+	if !slices.Equal(dst.Notifier.WebhookURLs, src.Notifier.WebhookURLs) {
+		t.Errorf(
+			"notifier.webhook_urls = %v, want %v",
+			dst.Notifier.WebhookURLs, src.Notifier.WebhookURLs,
+		)
+	}
+
+	if dst.Server.Port == src.Server.Port {
+		t.Error("server.port requires restart and must not be copied")
+	}
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/slo"
+	"github.com/dzherb/mifi-go-microservice/tracing"
+)
+
+// Validate проверяет всю конфигурацию и возвращает все найденные
+// ошибки разом, а не только первую
+func (c Config) Validate() error {
+	var v validator
+
+	v.port("server.port", c.Server.Port)
+	v.positiveDuration(
+		"server.read_header_timeout",
+		c.Server.ReadHeaderTimeout,
+	)
+	v.positiveDuration("server.read_timeout", c.Server.ReadTimeout)
+	v.positiveDuration("server.write_timeout", c.Server.WriteTimeout)
+	v.positiveDuration("server.idle_timeout", c.Server.IdleTimeout)
+	v.positive("server.max_header_bytes", c.Server.MaxHeaderBytes)
+
+	if c.Server.ReadHeaderTimeout > c.Server.ReadTimeout {
+		v.fail(
+			"server.read_header_timeout",
+			"must not exceed server.read_timeout",
+		)
+	}
+
+	v.pair(
+		"server.tls.cert_file", c.Server.TLS.CertFile,
+		"server.tls.key_file", c.Server.TLS.KeyFile,
+	)
+
+	switch c.Server.TLS.MinVersion {
+	case "1.2", "1.3":
+	default:
+		v.fail("server.tls.min_version", "must be 1.2 or 1.3")
+	}
+
+	if c.Server.TLS.ClientCAFile != "" && c.Server.TLS.CertFile == "" {
+		v.fail("server.tls.client_ca_file", "requires server.tls.cert_file")
+	}
+
+	v.port("admin.port", c.Admin.Port)
+
+	if sameListener(c.Admin.Host, c.Admin.Port, c.Server.Host, c.Server.Port) {
+		v.fail("admin.port", "must differ from server.port")
+	}
+
+	if c.GRPC.Enabled {
+		v.port("grpc.port", c.GRPC.Port)
+
+		if sameListener(c.GRPC.Host, c.GRPC.Port, c.Server.Host, c.Server.Port) {
+			v.fail("grpc.port", "must differ from server.port")
+		}
+
+		if sameListener(c.GRPC.Host, c.GRPC.Port, c.Admin.Host, c.Admin.Port) {
+			v.fail("grpc.port", "must differ from admin.port")
+		}
+	}
+
+	if c.API.MaxBodyBytes < 0 {
+		v.fail("api.max_body_bytes", "must not be negative")
+	}
+
+	v.positive("api.max_requests_per_second", c.API.MaxRequestsPerSecond)
+	v.positive("api.max_burst", c.API.MaxBurst)
+	v.nonNegative("api.max_concurrent_requests", c.API.MaxConcurrentRequests)
+	v.nonNegative("api.reserved_read_slots", c.API.ReservedReadSlots)
+
+	if c.API.MaxConcurrentRequests > 0 &&
+		c.API.ReservedReadSlots >= c.API.MaxConcurrentRequests {
+		v.fail(
+			"api.reserved_read_slots",
+			"must be less than api.max_concurrent_requests",
+		)
+	}
+
+	v.positiveDuration(
+		"api.concurrency_queue_timeout",
+		c.API.ConcurrencyQueueTimeout,
+	)
+	v.positiveDuration("api.shed_retry_after", c.API.ShedRetryAfter)
+	v.fraction(
+		"api.access_log_success_sample_rate",
+		c.API.AccessLogSuccessSampleRate,
+	)
+
+	v.required("minio.endpoint", c.MinIO.Endpoint)
+	v.pair(
+		"minio.access_key", c.MinIO.AccessKey,
+		"minio.secret_key", c.MinIO.SecretKey,
+	)
+	v.pair(
+		"minio.access_key_file", c.MinIO.AccessKeyFile,
+		"minio.secret_key_file", c.MinIO.SecretKeyFile,
+	)
+	v.positiveDuration(
+		"minio.credentials_refresh_interval",
+		c.MinIO.CredentialsRefreshInterval,
+	)
+	v.required("minio.bucket", c.MinIO.Bucket)
+	v.required("minio.idempotency_bucket", c.MinIO.IdempotencyBucket)
+
+	if c.MinIO.Bucket == c.MinIO.IdempotencyBucket {
+		v.fail("minio.idempotency_bucket", "must differ from minio.bucket")
+	}
+
+	v.positiveDuration(
+		"users.count_refresh_interval",
+		c.Users.CountRefreshInterval,
+	)
+	v.positiveDuration("idempotency.key_ttl", c.Idempotency.KeyTTL)
+
+	for i, raw := range c.Notifier.WebhookURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			v.fail(
+				fmt.Sprintf("notifier.webhook_urls[%d]", i),
+				"must be an http or https URL",
+			)
+		}
+	}
+
+	v.positiveDuration("notifier.timeout", c.Notifier.Timeout)
+	v.positive("notifier.queue_size", c.Notifier.QueueSize)
+	v.positive("notifier.workers", c.Notifier.Workers)
+
+	v.positiveDuration("health.readiness_timeout", c.Health.ReadinessTimeout)
+
+	_, err := slo.ParseObjectives(c.SLO.Objectives)
+	v.check("slo.objectives", err)
+
+	for i, window := range c.SLO.Windows {
+		v.positiveDuration(fmt.Sprintf("slo.windows[%d]", i), window)
+	}
+
+	v.positiveDuration("slo.sample_interval", c.SLO.SampleInterval)
+
+	v.logLevel("log.level", c.Log.Level, false)
+	v.logFormat("log.format", c.Log.Format)
+	v.logLevel("log.stdout_level", c.Log.StdoutLevel, true)
+
+	if c.Log.File.Path != "" {
+		v.logFormat("log.file.format", c.Log.File.Format)
+		v.logLevel("log.file.level", c.Log.File.Level, true)
+		v.nonNegative("log.file.max_size_mb", c.Log.File.MaxSizeMB)
+		v.nonNegative("log.file.max_backups", c.Log.File.MaxBackups)
+		v.nonNegative("log.file.max_age_days", c.Log.File.MaxAgeDays)
+	}
+
+	_, err = logger.ParseSamplingRules(c.Log.Sampling, c.Log.SamplingInterval)
+	v.check("log.sampling", err)
+	v.positiveDuration("log.sampling_interval", c.Log.SamplingInterval)
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		v.required("tracing.file_path", c.Tracing.FilePath)
+	default:
+		v.fail("tracing.exporter", "must be none, otlp or file")
+	}
+
+	v.required("tracing.service_name", c.Tracing.ServiceName)
+	v.fraction("tracing.sample_ratio", c.Tracing.SampleRatio)
+
+	if c.Reload.WatchInterval < 0 {
+		v.fail("reload.watch_interval", "must not be negative")
+	}
+
+	if c.Shutdown.DrainDelay < 0 {
+		v.fail("shutdown.drain_delay", "must not be negative")
+	}
+
+	v.positiveDuration("shutdown.http_timeout", c.Shutdown.HTTPTimeout)
+	v.positiveDuration("shutdown.grpc_timeout", c.Shutdown.GRPCTimeout)
+	v.positiveDuration(
+		"shutdown.handlers_timeout",
+		c.Shutdown.HandlersTimeout,
+	)
+	v.positiveDuration(
+		"shutdown.notifier_timeout",
+		c.Shutdown.NotifierTimeout,
+	)
+	v.positiveDuration("shutdown.storage_timeout", c.Shutdown.StorageTimeout)
+	v.positiveDuration("shutdown.tracing_timeout", c.Shutdown.TracingTimeout)
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+// sameListener сообщает, что адреса займут один порт: сервер
+// на пустом хосте, 0.0.0.0 или :: слушает все интерфейсы и
+// конфликтует с любым хостом на том же порту
+func sameListener(hostA string, portA int, hostB string, portB int) bool {
+	if portA != portB {
+		return false
+	}
+
+	return hostA == hostB || isWildcardHost(hostA) || isWildcardHost(hostB)
+}
+
+func isWildcardHost(host string) bool {
+	switch host {
+	case "", "0.0.0.0", "::", "[::]":
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *validator) fail(path, msg string) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", path, msg))
+}
+
+func (v *validator) check(path string, err error) {
+	if err != nil {
+		v.errs = append(v.errs, fmt.Errorf("%s: %w", path, err))
+	}
+}
+
+func (v *validator) port(path string, port int) {
+	if port < 1 || port > 65535 {
+		v.fail(path, fmt.Sprintf("must be in [1, 65535], got %d", port))
+	}
+}
+
+func (v *validator) required(path, value string) {
+	if value == "" {
+		v.fail(path, "is required")
+	}
+}
+
+// pair требует, чтобы значения были заданы оба или ни одного
+func (v *validator) pair(path, value, otherPath, otherValue string) {
+	if (value == "") != (otherValue == "") {
+		v.fail(path, "must be set together with "+otherPath)
+	}
+}
+
+func (v *validator) fraction(path string, value float64) {
+	if value < 0 || value > 1 {
+		v.fail(path, fmt.Sprintf("must be in [0, 1], got %v", value))
+	}
+}
+
+func (v *validator) logLevel(path, level string, optional bool) {
+	if optional && level == "" {
+		return
+	}
+
+	_, err := logger.ParseLevel(level)
+	v.check(path, err)
+}
+
+func (v *validator) logFormat(path, format string) {
+	switch format {
+	case logger.FormatJSON, logger.FormatText, logger.FormatLogfmt:
+	default:
+		v.fail(path, "must be json, text or logfmt")
+	}
+}
+
+func (v *validator) positive(path string, value int) {
+	if value <= 0 {
+		v.fail(path, fmt.Sprintf("must be positive, got %d", value))
+	}
+}
+
+func (v *validator) positiveDuration(path string, value time.Duration) {
+	if value <= 0 {
+		v.fail(path, fmt.Sprintf("must be positive, got %s", value))
+	}
+}
+
+func (v *validator) nonNegative(path string, value int) {
+	if value < 0 {
+		v.fail(path, fmt.Sprintf("must not be negative, got %d", value))
+	}
+}
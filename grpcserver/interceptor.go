@@ -0,0 +1,333 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+)
+
+// requestIDMetadataKey - заголовок X-Request-ID в метаданных gRPC,
+// которые всегда в нижнем регистре
+const requestIDMetadataKey = "x-request-id"
+
+// Перехватчики повторяют middleware HTTP API. У каждого есть
+// unary и stream вариант; stream вариант подменяет контекст потока
+// через wrappedStream.
+
+func requestIDUnaryInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(withRequestID(ctx, log, info.FullMethod), req)
+	}
+}
+
+func requestIDStreamInterceptor(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := withRequestID(ss.Context(), log, info.FullMethod)
+
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// withRequestID берет идентификатор из метаданных или создает
+// новый, возвращает его клиенту в заголовке ответа и сохраняет
+// в контексте вместе с логгером запроса
+func withRequestID(
+	ctx context.Context,
+	log *slog.Logger,
+	method string,
+) context.Context {
+	var requestID string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+
+	if !middleware.IsValidRequestID(requestID) {
+		requestID = uuid.New().String()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+	requestLog := log.With(
+		slog.String("request_id", requestID),
+		slog.String("grpc_method", method),
+	)
+
+	ctx = reqctx.WithRequestID(ctx, requestID)
+
+	return logger.WithContext(ctx, requestLog)
+}
+
+func clientCertUnaryInterceptor(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	return handler(withPrincipal(ctx), req)
+}
+
+func clientCertStreamInterceptor(
+	srv any,
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx := withPrincipal(ss.Context())
+
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+// withPrincipal сохраняет идентификатор клиента из проверенного
+// сертификата mTLS, как ClientCertMiddleware
+func withPrincipal(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return ctx
+	}
+
+	principal := middleware.CertIdentity(tlsInfo.State.VerifiedChains[0][0])
+	if principal == "" {
+		return ctx
+	}
+
+	return reqctx.WithPrincipal(ctx, principal)
+}
+
+func accessLogUnaryInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		logCall(ctx, log, info.FullMethod, err, start)
+
+		return resp, err
+	}
+}
+
+func accessLogStreamInterceptor(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		logCall(ss.Context(), log, info.FullMethod, err, start)
+
+		return err
+	}
+}
+
+func logCall(
+	ctx context.Context,
+	log *slog.Logger,
+	method string,
+	err error,
+	start time.Time,
+) {
+	code := status.Code(err)
+
+	attrs := []slog.Attr{
+		slog.String("grpc_method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("principal", reqctx.Principal(ctx)),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+
+	logger.FromContext(ctx, log).LogAttrs(
+		ctx,
+		callLogLevel(code),
+		"grpc request",
+		attrs...,
+	)
+}
+
+// callLogLevel соответствует accessLogLevel: ошибки сервера -
+// Error, ошибки клиента - Warn. Unavailable сервер возвращает
+// сам при остановке, поэтому это не ошибка.
+func callLogLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK, codes.Canceled:
+		return slog.LevelInfo
+	case codes.Internal, codes.Unknown, codes.DataLoss,
+		codes.Unimplemented, codes.DeadlineExceeded:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+func metricsUnaryInterceptor(m *metric.Metrics) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		observeCall(m, info.FullMethod, err, start)
+
+		return resp, err
+	}
+}
+
+func metricsStreamInterceptor(m *metric.Metrics) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		m.GRPCActiveStreams.WithLabelValues(info.FullMethod).Inc()
+		defer m.GRPCActiveStreams.WithLabelValues(info.FullMethod).Dec()
+
+		err := handler(srv, ss)
+
+		observeCall(m, info.FullMethod, err, start)
+
+		return err
+	}
+}
+
+func observeCall(m *metric.Metrics, method string, err error, start time.Time) {
+	m.GRPCRequestsTotal.
+		WithLabelValues(method, status.Code(err).String()).
+		Inc()
+	m.GRPCRequestDuration.
+		WithLabelValues(method).
+		Observe(time.Since(start).Seconds())
+}
+
+// Лимиты общие с HTTP API: вызовы gRPC тратят тот же token bucket
+// и занимают те же слоты, что и HTTP запросы.
+
+// readMethods занимают только общие слоты, как GET в HTTP API
+var readMethods = map[string]bool{
+	userv1.UserService_GetUser_FullMethodName:   true,
+	userv1.UserService_ListUsers_FullMethodName: true,
+}
+
+func rateLimitUnaryInterceptor(
+	limiter *middleware.RateLimiter,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if !limiter.Allow() {
+			return nil, errRateLimited
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitStreamInterceptor(
+	limiter *middleware.RateLimiter,
+) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !limiter.Allow() {
+			return errRateLimited
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+var errRateLimited = status.Error(
+	codes.ResourceExhausted,
+	"request rate limit exceeded",
+)
+
+func concurrencyLimitUnaryInterceptor(
+	limiter *middleware.ConcurrencyLimiter,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		release, _ := limiter.Acquire(ctx, readMethods[info.FullMethod])
+		if release == nil {
+			return nil, status.Error(
+				codes.Unavailable,
+				"server is overloaded, try again later",
+			)
+		}
+
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// Потоки не занимают слоты: WatchUsers живет, пока клиент
+// подписан, и несколько подписчиков заняли бы всю емкость API.
+// Открытие потока ограничивает только rateLimitStreamInterceptor.
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
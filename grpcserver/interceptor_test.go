@@ -0,0 +1,89 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+)
+
+func getUser(
+	ctx context.Context,
+	client userv1.UserServiceClient,
+) codes.Code {
+	_, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: uuid.NewString()})
+
+	return status.Code(err)
+}
+
+func TestRateLimitReturnsResourceExhausted(t *testing.T) {
+	limiter := middleware.NewRateLimiter(slog.New(slog.DiscardHandler), 0, 1)
+
+	client := newTestClient(t, fakeUserService{
+		get: func(context.Context, string) (model.User, error) {
+			return model.User{}, nil
+		},
+	}, testLimits{rate: limiter})
+
+	if code := getUser(t.Context(), client); code != codes.OK {
+		t.Fatalf("first call code = %v, want OK", code)
+	}
+
+	if code := getUser(t.Context(), client); code != codes.ResourceExhausted {
+		t.Fatalf("second call code = %v, want ResourceExhausted", code)
+	}
+}
+
+func TestConcurrencyLimitReturnsUnavailable(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	client := newTestClient(t, fakeUserService{
+		get: func(ctx context.Context, _ string) (model.User, error) {
+			started <- struct{}{}
+
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+
+			return model.User{}, nil
+		},
+	}, testLimits{
+		concurrency: middleware.ConcurrencyLimitConfig{
+			MaxInFlight:  1,
+			QueueTimeout: 50 * time.Millisecond,
+		},
+	})
+
+	first := make(chan codes.Code)
+
+	go func() { first <- getUser(t.Context(), client) }()
+
+	<-started
+
+	if code := getUser(t.Context(), client); code != codes.Unavailable {
+		t.Fatalf("call over the limit code = %v, want Unavailable", code)
+	}
+
+	close(release)
+
+	if code := <-first; code != codes.OK {
+		t.Fatalf("first call code = %v, want OK", code)
+	}
+
+	// слот освобожден после завершения вызова
+	go func() { <-started }()
+
+	if code := getUser(t.Context(), client); code != codes.OK {
+		t.Fatalf("call after release code = %v, want OK", code)
+	}
+}
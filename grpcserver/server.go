@@ -0,0 +1,151 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/server"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+)
+
+type Config struct {
+	Host string
+	Port int
+	// TLS настраивается так же, как у HTTP сервера API
+	TLS server.TLSConfig
+}
+
+// Server - gRPC сервер API на отдельном порту
+type Server struct {
+	log    *slog.Logger
+	addr   string
+	grpc   *grpc.Server
+	health *health.Server
+
+	// stopping закрывается в начале остановки, чтобы завершить
+	// WatchUsers: иначе GracefulStop ждал бы, пока клиенты
+	// сами отменят подписку
+	stopping     chan struct{}
+	stoppingOnce sync.Once
+}
+
+func New(
+	log *slog.Logger,
+	metrics *metric.Metrics,
+	userService UserService,
+	notifier Notifier,
+	rateLimiter *middleware.RateLimiter,
+	concurrencyLimiter *middleware.ConcurrencyLimiter,
+	cfg Config,
+) (*Server, error) {
+	s := &Server{
+		log:      log,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		health:   health.NewServer(),
+		stopping: make(chan struct{}),
+	}
+
+	// порядок как у middleware HTTP API: идентификатор запроса,
+	// клиент из mTLS, журнал и метрики, затем ограничители, чтобы
+	// отклоненные вызовы попали в журнал и метрики
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			requestIDUnaryInterceptor(log),
+			clientCertUnaryInterceptor,
+			accessLogUnaryInterceptor(log),
+			metricsUnaryInterceptor(metrics),
+			rateLimitUnaryInterceptor(rateLimiter),
+			concurrencyLimitUnaryInterceptor(concurrencyLimiter),
+		),
+		grpc.ChainStreamInterceptor(
+			requestIDStreamInterceptor(log),
+			clientCertStreamInterceptor,
+			accessLogStreamInterceptor(log),
+			metricsStreamInterceptor(metrics),
+			rateLimitStreamInterceptor(rateLimiter),
+		),
+	}
+
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := server.NewTLSConfig(log, cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("configuring TLS: %w", err)
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	s.grpc = grpc.NewServer(opts...)
+
+	userv1.RegisterUserServiceServer(
+		s.grpc,
+		newUserServer(log, metrics, userService, notifier, s.stopping),
+	)
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// ListenAndServe блокируется до остановки сервера; после
+// Shutdown возвращает nil
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.addr, err)
+	}
+
+	err = s.grpc.Serve(lis)
+	if err != nil {
+		return fmt.Errorf("serving gRPC: %w", err)
+	}
+
+	return nil
+}
+
+// MarkNotServing переводит health сервис в NOT_SERVING, чтобы
+// клиенты и балансировщики перестали выбирать этот сервер еще
+// до остановки; активные и новые вызовы при этом обслуживаются
+func (s *Server) MarkNotServing() {
+	s.health.Shutdown()
+}
+
+// Shutdown перестает принимать вызовы и ждет завершения активных;
+// по истечении ctx оставшиеся вызовы обрываются
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stoppingOnce.Do(func() { close(s.stopping) })
+	s.MarkNotServing()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		<-stopped
+
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,123 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+	"github.com/dzherb/mifi-go-microservice/service"
+)
+
+// fakeUserService возвращает результат get для всех операций
+// чтения; остальные операции не нужны тестам
+type fakeUserService struct {
+	get func(context.Context, string) (model.User, error)
+}
+
+func (f fakeUserService) Create(
+	_ context.Context,
+	user model.User,
+) (model.User, error) {
+	return user, nil
+}
+
+func (f fakeUserService) Get(ctx context.Context, id string) (model.User, error) {
+	return f.get(ctx, id)
+}
+
+func (f fakeUserService) GetAll(context.Context) ([]model.User, error) {
+	return nil, nil
+}
+
+func (f fakeUserService) Update(context.Context, model.User) error {
+	return nil
+}
+
+func (f fakeUserService) Delete(context.Context, string) error {
+	return nil
+}
+
+func (f fakeUserService) Subscribe(context.Context) <-chan service.UserEvent {
+	return nil
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) Send(context.Context, string, map[string]any) {}
+
+type testLimits struct {
+	rate        *middleware.RateLimiter
+	concurrency middleware.ConcurrencyLimitConfig
+}
+
+// newTestClient запускает сервер на bufconn и возвращает клиента к нему
+func newTestClient(
+	t *testing.T,
+	users UserService,
+	limits testLimits,
+) userv1.UserServiceClient {
+	t.Helper()
+
+	log := slog.New(slog.DiscardHandler)
+	m := metric.New(prometheus.NewRegistry())
+
+	if limits.rate == nil {
+		limits.rate = middleware.NewRateLimiter(log, 1000, 1000)
+	}
+
+	srv, err := New(
+		log,
+		m,
+		users,
+		nopNotifier{},
+		limits.rate,
+		middleware.NewConcurrencyLimiter(log, m, limits.concurrency),
+		Config{},
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+
+	served := make(chan error, 1)
+
+	go func() { served <- srv.grpc.Serve(lis) }()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(
+			ctx context.Context,
+			_ string,
+		) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		srv.grpc.Stop()
+
+		err := <-served
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+
+	return userv1.NewUserServiceClient(conn)
+}
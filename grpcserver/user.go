@@ -0,0 +1,274 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/service"
+)
+
+type UserService interface {
+	Create(context.Context, model.User) (model.User, error)
+	Get(context.Context, string) (model.User, error)
+	GetAll(context.Context) ([]model.User, error)
+	Update(context.Context, model.User) error
+	Delete(context.Context, string) error
+	Subscribe(context.Context) <-chan service.UserEvent
+}
+
+type Notifier interface {
+	Send(context.Context, string, map[string]any)
+}
+
+type userServer struct {
+	userv1.UnimplementedUserServiceServer
+
+	log      *slog.Logger
+	metrics  *metric.Metrics
+	service  UserService
+	notifier Notifier
+	stopping <-chan struct{}
+}
+
+func newUserServer(
+	log *slog.Logger,
+	metrics *metric.Metrics,
+	userService UserService,
+	notifier Notifier,
+	stopping <-chan struct{},
+) *userServer {
+	return &userServer{
+		log:      log,
+		metrics:  metrics,
+		service:  userService,
+		notifier: notifier,
+		stopping: stopping,
+	}
+}
+
+func (s *userServer) CreateUser(
+	ctx context.Context,
+	req *userv1.CreateUserRequest,
+) (*userv1.CreateUserResponse, error) {
+	user := model.User{
+		Name:  req.GetName(),
+		Email: req.GetEmail(),
+	}
+
+	if err := s.validate(user); err != nil {
+		return nil, err
+	}
+
+	created, err := s.service.Create(ctx, user)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to create user", err)
+	}
+
+	s.notifier.Send(
+		context.WithoutCancel(ctx),
+		"user_created",
+		map[string]any{
+			"user_id": created.ID,
+		},
+	)
+
+	return &userv1.CreateUserResponse{User: toProtoUser(created)}, nil
+}
+
+func (s *userServer) GetUser(
+	ctx context.Context,
+	req *userv1.GetUserRequest,
+) (*userv1.GetUserResponse, error) {
+	if err := validateUserID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to get user", err)
+	}
+
+	return &userv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s *userServer) ListUsers(
+	ctx context.Context,
+	_ *userv1.ListUsersRequest,
+) (*userv1.ListUsersResponse, error) {
+	users, err := s.service.GetAll(ctx)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to list users", err)
+	}
+
+	resp := &userv1.ListUsersResponse{
+		Users: make([]*userv1.User, 0, len(users)),
+	}
+
+	for _, user := range users {
+		resp.Users = append(resp.Users, toProtoUser(user))
+	}
+
+	return resp, nil
+}
+
+func (s *userServer) UpdateUser(
+	ctx context.Context,
+	req *userv1.UpdateUserRequest,
+) (*userv1.UpdateUserResponse, error) {
+	if err := validateUserID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	user := model.User{
+		ID:    req.GetId(),
+		Name:  req.GetName(),
+		Email: req.GetEmail(),
+	}
+
+	if err := s.validate(user); err != nil {
+		return nil, err
+	}
+
+	err := s.service.Update(ctx, user)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to update user", err)
+	}
+
+	return &userv1.UpdateUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s *userServer) DeleteUser(
+	ctx context.Context,
+	req *userv1.DeleteUserRequest,
+) (*userv1.DeleteUserResponse, error) {
+	if err := validateUserID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	err := s.service.Delete(ctx, req.GetId())
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to delete user", err)
+	}
+
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+// WatchUsers завершается, когда клиент отменяет вызов
+// или сервер начинает остановку
+func (s *userServer) WatchUsers(
+	_ *userv1.WatchUsersRequest,
+	stream grpc.ServerStreamingServer[userv1.WatchUsersResponse],
+) error {
+	ctx := stream.Context()
+	events := s.service.Subscribe(ctx)
+
+	for {
+		select {
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+
+			err := stream.Send(&userv1.WatchUsersResponse{
+				Type: toProtoEventType(event.Type),
+				User: toProtoUser(event.User),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// validate возвращает InvalidArgument с описанием поля
+// в деталях BadRequest, как 400 в HTTP API
+func (s *userServer) validate(user model.User) error {
+	var validationErr *model.ValidationError
+	if !errors.As(user.Validate(), &validationErr) {
+		return nil
+	}
+
+	s.metrics.UserValidationFailuresTotal.
+		WithLabelValues(validationErr.Field).
+		Inc()
+
+	st := status.New(codes.InvalidArgument, validationErr.Error())
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{
+				Field:       validationErr.Field,
+				Description: validationErr.Message,
+			},
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+func validateUserID(id string) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	return nil
+}
+
+// toStatus переводит ошибки сервиса в коды gRPC; внутренние
+// ошибки логируются, а клиенту уходит только Internal
+func (s *userServer) toStatus(ctx context.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserDoesNotExist):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrMissingUserID):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	logger.FromContext(ctx, s.log).ErrorContext(
+		ctx,
+		msg,
+		slog.String("error", err.Error()),
+	)
+
+	return status.Error(codes.Internal, "something went wrong")
+}
+
+func toProtoUser(user model.User) *userv1.User {
+	return &userv1.User{
+		Id:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+	}
+}
+
+func toProtoEventType(t service.UserEventType) userv1.UserEventType {
+	switch t {
+	case service.UserCreated:
+		return userv1.UserEventType_USER_EVENT_TYPE_CREATED
+	case service.UserUpdated:
+		return userv1.UserEventType_USER_EVENT_TYPE_UPDATED
+	case service.UserDeleted:
+		return userv1.UserEventType_USER_EVENT_TYPE_DELETED
+	default:
+		return userv1.UserEventType_USER_EVENT_TYPE_UNSPECIFIED
+	}
+}
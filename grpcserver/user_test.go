@@ -0,0 +1,101 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userv1 "github.com/dzherb/mifi-go-microservice/gen/user/v1"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/service"
+)
+
+func TestGetUserStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{
+			name:    "missing user",
+			id:      uuid.NewString(),
+			err:     service.ErrUserDoesNotExist,
+			code:    codes.NotFound,
+			message: service.ErrUserDoesNotExist.Error(),
+		},
+		{
+			name:    "invalid id",
+			id:      "not-a-uuid",
+			code:    codes.InvalidArgument,
+			message: "invalid user ID",
+		},
+		{
+			// подробности внутренних ошибок клиенту не уходят
+			name:    "storage failure",
+			id:      uuid.NewString(),
+			err:     errors.New("bucket users: connection refused"),
+			code:    codes.Internal,
+			message: "something went wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, fakeUserService{
+				get: func(context.Context, string) (model.User, error) {
+					return model.User{}, tt.err
+				},
+			}, testLimits{})
+
+			_, err := client.GetUser(
+				t.Context(),
+				&userv1.GetUserRequest{Id: tt.id},
+			)
+
+			st := status.Convert(err)
+			if st.Code() != tt.code || st.Message() != tt.message {
+				t.Fatalf(
+					"status = %v %q, want %v %q",
+					st.Code(), st.Message(), tt.code, tt.message,
+				)
+			}
+		})
+	}
+}
+
+func TestCreateUserValidationDetails(t *testing.T) {
+	client := newTestClient(t, fakeUserService{}, testLimits{})
+
+	_, err := client.CreateUser(
+		t.Context(),
+		&userv1.CreateUserRequest{Name: "a", Email: "not an email"},
+	)
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v, want InvalidArgument", st.Code())
+	}
+
+	var badRequest *errdetails.BadRequest
+
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.BadRequest); ok {
+			badRequest = d
+		}
+	}
+
+	if badRequest == nil || len(badRequest.GetFieldViolations()) != 1 {
+		t.Fatalf("details = %v, want one BadRequest violation", st.Details())
+	}
+
+	if field := badRequest.GetFieldViolations()[0].GetField(); field != "email" {
+		t.Fatalf("violation field = %q, want email", field)
+	}
+}
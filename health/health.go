@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+var (
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+type CheckFunc func(context.Context) error
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker выполняет зарегистрированные проверки готовности
+type Checker struct {
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{timeout: timeout}
+
+	c.Register("shutdown", func(context.Context) error {
+		if c.shuttingDown.Load() {
+			return ErrShuttingDown
+		}
+
+		return nil
+	})
+
+	return c
+}
+
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// MarkShuttingDown переводит готовность в состояние ошибки,
+// чтобы балансировщик перестал направлять трафик
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, nc := range checks {
+		wg.Go(func() {
+			results[i] = runCheck(ctx, nc.check)
+		})
+	}
+
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check CheckFunc) CheckResult {
+	start := time.Now()
+	err := check(ctx)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		return CheckResult{
+			Status:    StatusFail,
+			LatencyMS: latency,
+			Error:     err.Error(),
+		}
+	}
+
+	return CheckResult{Status: StatusOK, LatencyMS: latency}
+}
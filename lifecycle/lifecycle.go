@@ -0,0 +1,177 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PhaseFunc выполняет одну фазу остановки; ctx отменяется
+// по истечении таймаута фазы
+type PhaseFunc func(ctx context.Context) error
+
+type phase struct {
+	name    string
+	timeout time.Duration
+	run     PhaseFunc
+}
+
+// Manager останавливает сервис по фазам в порядке добавления.
+// Каждая фаза получает собственный таймаут, а ошибка или таймаут
+// одной фазы не мешают выполнить следующие. Manager не подписывается
+// на сигналы сам, поэтому его можно запускать из тестов.
+type Manager struct {
+	log *slog.Logger
+
+	mu       sync.Mutex
+	phases   []phase
+	shutdown sync.Once
+	err      error
+}
+
+func New(log *slog.Logger) *Manager {
+	return &Manager{log: log}
+}
+
+// AddPhase добавляет фазу; timeout <= 0 означает без таймаута
+func (m *Manager) AddPhase(name string, timeout time.Duration, run PhaseFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.phases = append(m.phases, phase{name: name, timeout: timeout, run: run})
+}
+
+type stoppedKey struct{}
+
+// Run ждет отмены ctx или значения из stop и выполняет остановку;
+// во втором случае фазы узнают об этом через Stopped
+func (m *Manager) Run(ctx context.Context, stop <-chan struct{}) error {
+	shutdownCtx := context.WithoutCancel(ctx)
+
+	select {
+	case <-ctx.Done():
+	case <-stop:
+		shutdownCtx = context.WithValue(shutdownCtx, stoppedKey{}, true)
+	}
+
+	return m.Shutdown(shutdownCtx)
+}
+
+// Stopped сообщает, что остановку начал stop из Run, например
+// упавший сервер, а не отмена контекста сигналом
+func Stopped(ctx context.Context) bool {
+	stopped, _ := ctx.Value(stoppedKey{}).(bool)
+
+	return stopped
+}
+
+// Shutdown выполняет все фазы один раз; повторные вызовы
+// возвращают результат первого
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.shutdown.Do(func() {
+		m.mu.Lock()
+		phases := m.phases
+		m.mu.Unlock()
+
+		m.log.Info("shutting down", slog.Int("phases", len(phases)))
+
+		var errs []error
+
+		for _, p := range phases {
+			err := m.runPhase(ctx, p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("phase %s: %w", p.name, err))
+			}
+		}
+
+		m.err = errors.Join(errs...)
+	})
+
+	return m.err
+}
+
+func (m *Manager) runPhase(ctx context.Context, p phase) error {
+	log := m.log.With(slog.String("phase", p.name))
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	log.Info("shutdown phase started", slog.Duration("timeout", p.timeout))
+
+	start := time.Now()
+
+	err := p.run(ctx)
+	if err == nil && ctx.Err() != nil {
+		// фаза могла вернуться без ошибки, не дождавшись завершения
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		log.Error(
+			"shutdown phase failed",
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+
+		return err
+	}
+
+	log.Info(
+		"shutdown phase completed",
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return nil
+}
+
+// Delay - фаза, которая просто ждет d, например чтобы балансировщик
+// успел заметить неготовность и перестал присылать запросы
+func Delay(d time.Duration) PhaseFunc {
+	return func(ctx context.Context) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ShutdownServers - фаза, которая параллельно останавливает
+// HTTP серверы: они перестают принимать соединения и ждут
+// завершения активных запросов
+func ShutdownServers(servers map[string]*http.Server) PhaseFunc {
+	return func(ctx context.Context) error {
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+
+		for name, s := range servers {
+			wg.Go(func() {
+				err := s.Shutdown(ctx)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s server: %w", name, err))
+					mu.Unlock()
+				}
+			})
+		}
+
+		wg.Wait()
+
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,180 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newManager() *Manager {
+	return New(slog.New(slog.DiscardHandler))
+}
+
+func TestShutdownRunsPhasesInOrder(t *testing.T) {
+	m := newManager()
+
+	var order []string
+
+	for _, name := range []string{"first", "second", "third"} {
+		m.AddPhase(name, 0, func(context.Context) error {
+			order = append(order, name)
+
+			return nil
+		})
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	want := []string{"first", "second", "third"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("phases ran in order %v, want %v", order, want)
+	}
+}
+
+func TestShutdownPhaseTimeout(t *testing.T) {
+	m := newManager()
+
+	nextRan := false
+
+	m.AddPhase("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return nil
+	})
+	m.AddPhase("next", time.Second, func(ctx context.Context) error {
+		// таймаут предыдущей фазы не должен сокращать следующую
+		if ctx.Err() != nil {
+			t.Errorf("next phase context is done: %v", ctx.Err())
+		}
+
+		nextRan = true
+
+		return nil
+	})
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+
+	if !nextRan {
+		t.Fatal("phase after timed out phase did not run")
+	}
+}
+
+func TestShutdownJoinsErrors(t *testing.T) {
+	m := newManager()
+
+	errFirst := errors.New("first failed")
+	errThird := errors.New("third failed")
+
+	m.AddPhase("first", 0, func(context.Context) error { return errFirst })
+	m.AddPhase("second", 0, func(context.Context) error { return nil })
+	m.AddPhase("third", 0, func(context.Context) error { return errThird })
+
+	err := m.Shutdown(context.Background())
+
+	if !errors.Is(err, errFirst) || !errors.Is(err, errThird) {
+		t.Fatalf("Shutdown() error = %v, want both phase errors", err)
+	}
+}
+
+func TestShutdownRunsOnce(t *testing.T) {
+	m := newManager()
+
+	errPhase := errors.New("phase failed")
+	calls := 0
+
+	m.AddPhase("counted", 0, func(context.Context) error {
+		calls++
+
+		return errPhase
+	})
+
+	first := m.Shutdown(context.Background())
+	second := m.Shutdown(context.Background())
+
+	if calls != 1 {
+		t.Fatalf("phase ran %d times, want 1", calls)
+	}
+
+	if !errors.Is(second, errPhase) || first.Error() != second.Error() {
+		t.Fatalf("second Shutdown() = %v, want %v", second, first)
+	}
+}
+
+func TestRunReportsStopCause(t *testing.T) {
+	tests := []struct {
+		name        string
+		trigger     func(cancel context.CancelFunc, stop chan struct{})
+		wantStopped bool
+	}{
+		{
+			name: "context canceled",
+			trigger: func(cancel context.CancelFunc, _ chan struct{}) {
+				cancel()
+			},
+			wantStopped: false,
+		},
+		{
+			name: "stop channel",
+			trigger: func(_ context.CancelFunc, stop chan struct{}) {
+				stop <- struct{}{}
+			},
+			wantStopped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			stop := make(chan struct{}, 1)
+
+			m := newManager()
+
+			var stopped bool
+
+			m.AddPhase("check", 0, func(ctx context.Context) error {
+				stopped = Stopped(ctx)
+
+				return nil
+			})
+
+			tt.trigger(cancel, stop)
+
+			if err := m.Run(ctx, stop); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if stopped != tt.wantStopped {
+				t.Fatalf("Stopped() = %v, want %v", stopped, tt.wantStopped)
+			}
+		})
+	}
+}
+
+func TestDelayStopsOnContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond,
+	)
+	defer cancel()
+
+	start := time.Now()
+
+	err := Delay(time.Minute)(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Delay() error = %v, want deadline exceeded", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatal("Delay() did not return on context timeout")
+	}
+}
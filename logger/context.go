@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+func WithContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext возвращает логгер запроса, если он есть в контексте,
+// иначе fallback. Если fallback именованный, логгер запроса получает
+// то же имя, чтобы на него распространялся уровень этого логгера
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	log, ok := ctx.Value(contextKey{}).(*slog.Logger)
+	if !ok {
+		return fallback
+	}
+
+	if name := loggerName(fallback); name != "" && name != loggerName(log) {
+		return Named(log, name)
+	}
+
+	return log
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// LoggerKey - атрибут, по которому именованные логгеры получают
+// собственный уровень логирования
+const LoggerKey = "logger"
+
+// Levels хранит уровень корневого логгера и переопределения
+// для именованных дочерних логгеров, все они меняются на лету
+type Levels struct {
+	root *slog.LevelVar
+
+	mu    sync.RWMutex
+	named map[string]*slog.LevelVar
+}
+
+func newLevels(lvl slog.Level) *Levels {
+	root := new(slog.LevelVar)
+	root.Set(lvl)
+
+	return &Levels{
+		root:  root,
+		named: make(map[string]*slog.LevelVar),
+	}
+}
+
+func ParseLevel(name string) (slog.Level, error) {
+	var lvl slog.Level
+
+	err := lvl.UnmarshalText([]byte(name))
+	if err != nil {
+		return lvl, fmt.Errorf("parse log level %q: %w", name, err)
+	}
+
+	return lvl, nil
+}
+
+func (l *Levels) Level() slog.Level {
+	return l.root.Level()
+}
+
+func (l *Levels) SetLevel(lvl slog.Level) {
+	l.root.Set(lvl)
+}
+
+// SetLoggerLevel переопределяет уровень именованного логгера
+func (l *Levels) SetLoggerLevel(name string, lvl slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, ok := l.named[name]
+	if !ok {
+		v = new(slog.LevelVar)
+		l.named[name] = v
+	}
+
+	v.Set(lvl)
+}
+
+// ResetLoggerLevel возвращает именованный логгер к корневому уровню
+func (l *Levels) ResetLoggerLevel(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.named, name)
+}
+
+// LoggerLevels возвращает текущие переопределения уровней
+func (l *Levels) LoggerLevels() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make(map[string]slog.Level, len(l.named))
+
+	for name, v := range l.named {
+		result[name] = v.Level()
+	}
+
+	return result
+}
+
+func (l *Levels) levelFor(name string) slog.Level {
+	if name != "" {
+		l.mu.RLock()
+		v, ok := l.named[name]
+		l.mu.RUnlock()
+
+		if ok {
+			return v.Level()
+		}
+	}
+
+	return l.root.Level()
+}
+
+// Named создает дочерний логгер, уровень которого можно
+// менять независимо от корневого через Levels.SetLoggerLevel
+func Named(log *slog.Logger, name string) *slog.Logger {
+	return log.With(slog.String(LoggerKey, name))
+}
+
+// levelHandler отбрасывает записи ниже текущего уровня;
+// имя логгера запоминается, когда к нему добавляется атрибут LoggerKey.
+// Сам атрибут добавляется к записи один раз при обработке, поэтому
+// повторный Named, например в FromContext, заменяет имя, а не
+// дублирует ключ.
+type levelHandler struct {
+	next   slog.Handler
+	levels *Levels
+	name   string
+	// grouped - после WithGroup имя уже передано в next, и атрибуты
+	// передаются как есть
+	grouped bool
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return lvl >= h.levels.levelFor(h.name) && h.next.Enabled(ctx, lvl)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if !h.grouped && h.name != "" {
+		rec = rec.Clone()
+		rec.AddAttrs(slog.String(LoggerKey, h.name))
+	}
+
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	name := h.name
+	rest := make([]slog.Attr, 0, len(attrs))
+
+	for _, attr := range attrs {
+		if attr.Key == LoggerKey && attr.Value.Kind() == slog.KindString {
+			name = attr.Value.String()
+
+			if !h.grouped {
+				continue
+			}
+		}
+
+		rest = append(rest, attr)
+	}
+
+	next := h.next
+	if len(rest) > 0 {
+		next = next.WithAttrs(rest)
+	}
+
+	return &levelHandler{
+		next:    next,
+		levels:  h.levels,
+		name:    name,
+		grouped: h.grouped,
+	}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	next := h.next
+	if !h.grouped && h.name != "" {
+		next = next.WithAttrs([]slog.Attr{slog.String(LoggerKey, h.name)})
+	}
+
+	return &levelHandler{
+		next:    next.WithGroup(name),
+		levels:  h.levels,
+		name:    h.name,
+		grouped: true,
+	}
+}
+
+func loggerName(log *slog.Logger) string {
+	if h, ok := log.Handler().(*levelHandler); ok {
+		return h.name
+	}
+
+	return ""
+}
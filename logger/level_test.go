@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogger собирает ту же цепочку обработчиков, что и New,
+// но пишет JSON в буфер
+func newTestLogger(
+	t *testing.T,
+	lvl slog.Level,
+	sampling []SamplingRule,
+	redact RedactConfig,
+) (*slog.Logger, *Levels, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	var h slog.Handler = slog.NewJSONHandler(
+		&buf,
+		&slog.HandlerOptions{Level: slog.Level(minLevel)},
+	)
+	h = newSamplingHandler(h, sampling)
+	h = newRedactHandler(h, redact)
+
+	levels := newLevels(lvl)
+
+	return slog.New(&levelHandler{next: h, levels: levels}), levels, &buf
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var result []map[string]any
+
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var rec map[string]any
+
+		err := json.Unmarshal([]byte(line), &rec)
+		if err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+
+		result = append(result, rec)
+	}
+
+	return result
+}
+
+func TestNamedLevelOverridesRoot(t *testing.T) {
+	log, levels, buf := newTestLogger(t, slog.LevelInfo, nil, RedactConfig{})
+
+	storage := Named(log, "storage")
+	http := Named(log, "http")
+
+	levels.SetLoggerLevel("storage", slog.LevelDebug)
+	levels.SetLoggerLevel("http", slog.LevelError)
+
+	log.Debug("root debug")
+	storage.Debug("storage debug")
+	http.Warn("http warn")
+	http.Error("http error")
+
+	got := records(t, buf)
+	if len(got) != 2 ||
+		got[0]["msg"] != "storage debug" ||
+		got[1]["msg"] != "http error" {
+		t.Fatalf("records = %v, want storage debug and http error", got)
+	}
+
+	buf.Reset()
+	levels.ResetLoggerLevel("http")
+
+	http.Warn("http warn after reset")
+
+	if got := records(t, buf); len(got) != 1 {
+		t.Fatalf("records after reset = %v, want the warning", got)
+	}
+
+	want := map[string]slog.Level{"storage": slog.LevelDebug}
+	if got := levels.LoggerLevels(); len(got) != 1 ||
+		got["storage"] != want["storage"] {
+		t.Fatalf("LoggerLevels() = %v, want %v", got, want)
+	}
+}
+
+func TestRootLevelChangesApplyToNamedLoggers(t *testing.T) {
+	log, levels, buf := newTestLogger(t, slog.LevelInfo, nil, RedactConfig{})
+
+	storage := Named(log, "storage")
+
+	storage.Debug("hidden")
+	levels.SetLevel(slog.LevelDebug)
+	storage.Debug("visible")
+
+	got := records(t, buf)
+	if len(got) != 1 || got[0]["msg"] != "visible" {
+		t.Fatalf("records = %v, want only the message after SetLevel", got)
+	}
+}
+
+func TestFromContextKeepsFallbackName(t *testing.T) {
+	log, levels, buf := newTestLogger(t, slog.LevelInfo, nil, RedactConfig{})
+
+	requestLog := log.With(slog.String("request_id", "r1"))
+	ctx := WithContext(context.Background(), requestLog)
+
+	storage := Named(log, "storage")
+
+	FromContext(ctx, storage).Info("stored")
+
+	got := records(t, buf)
+	if len(got) != 1 {
+		t.Fatalf("records = %v, want one", got)
+	}
+
+	if got[0][LoggerKey] != "storage" || got[0]["request_id"] != "r1" {
+		t.Fatalf("record = %v, want logger=storage and request_id=r1", got[0])
+	}
+
+	// уровень именованного логгера действует и на логгер запроса
+	buf.Reset()
+	levels.SetLoggerLevel("storage", slog.LevelError)
+
+	FromContext(ctx, storage).Info("suppressed")
+
+	if got := records(t, buf); len(got) != 0 {
+		t.Fatalf("records = %v, want none", got)
+	}
+
+	// без логгера в контексте возвращается fallback
+	if FromContext(context.Background(), storage) != storage {
+		t.Fatal("FromContext without logger must return fallback")
+	}
+}
+
+func TestNestedNamedWritesLoggerKeyOnce(t *testing.T) {
+	log, _, buf := newTestLogger(t, slog.LevelInfo, nil, RedactConfig{})
+
+	requestLog := Named(log, "http").With(slog.String("request_id", "r1"))
+	ctx := WithContext(context.Background(), requestLog)
+
+	tests := []struct {
+		name string
+		log  *slog.Logger
+		want string
+	}{
+		{"nested Named", Named(Named(log, "a"), "b"), "b"},
+		{"FromContext", FromContext(ctx, Named(log, "storage")), "storage"},
+		{"group", Named(log, "a").WithGroup("g").With("k", 1), "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+
+			tt.log.Info("msg")
+
+			line := buf.String()
+			if n := strings.Count(line, `"`+LoggerKey+`":`); n != 1 {
+				t.Fatalf("%q has %d %q keys, want 1", line, n, LoggerKey)
+			}
+
+			got := records(t, buf)
+			if got[0][LoggerKey] != tt.want {
+				t.Fatalf(
+					"%s = %v, want %q",
+					LoggerKey, got[0][LoggerKey], tt.want,
+				)
+			}
+		})
+	}
+}
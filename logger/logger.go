@@ -1,24 +1,78 @@
 package logger
 
 import (
+	"errors"
+	"io"
 	"log/slog"
-	"os"
+	"math"
 )
 
-func New(levelName string) *slog.Logger {
-	var lvl slog.Level
+// minLevel пропускает все записи, уровень проверяет levelHandler
+const minLevel = math.MinInt
 
-	err := lvl.UnmarshalText([]byte(levelName))
+type Config struct {
+	Level string
+	// Outputs - куда писать логи, по умолчанию JSON в stdout
+	Outputs  []OutputConfig
+	Sampling []SamplingRule
+	Redact   RedactConfig
+}
+
+type Logger struct {
+	*slog.Logger
+	Levels *Levels
+
+	closers []io.Closer
+}
+
+func New(cfg Config) (*Logger, error) {
+	lvl, err := ParseLevel(cfg.Level)
 	if err != nil {
-		panic("parse log level: " + err.Error())
+		return nil, err
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Path: OutputStdout, Format: FormatJSON}}
+	}
+
+	l := &Logger{Levels: newLevels(lvl)}
+
+	handlers := make([]slog.Handler, 0, len(outputs))
+
+	for _, output := range outputs {
+		h, closer, err := newOutputHandler(output)
+		if err != nil {
+			return nil, errors.Join(err, l.Close())
+		}
+
+		handlers = append(handlers, h)
+		l.closers = append(l.closers, closer)
 	}
 
-	return slog.New(
-		slog.NewJSONHandler(
-			os.Stdout,
-			&slog.HandlerOptions{
-				Level: lvl,
-			},
-		),
+	var handler slog.Handler = &fanoutHandler{handlers: handlers}
+
+	handler = traceHandler{Handler: handler}
+	handler = newSamplingHandler(handler, cfg.Sampling)
+	handler = newRedactHandler(handler, cfg.Redact)
+
+	l.Logger = slog.New(
+		&levelHandler{
+			next:   handler,
+			levels: l.Levels,
+		},
 	)
+
+	return l, nil
+}
+
+// Close закрывает файлы, в которые пишутся логи
+func (l *Logger) Close() error {
+	errs := make([]error, 0, len(l.closers))
+
+	for _, closer := range l.closers {
+		errs = append(errs, closer.Close())
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+type OutputConfig struct {
+	// Path - stdout, stderr или путь к файлу
+	Path   string
+	Format string
+	// Level - минимальный уровень записей для этого вывода
+	// поверх общего уровня, пустой не добавляет ограничений
+	Level string
+	// MaxSizeMB - размер файла, после которого он ротируется
+	MaxSizeMB int
+	// MaxBackups - сколько ротированных файлов хранить
+	MaxBackups int
+	// MaxAgeDays - сколько дней хранить ротированные файлы
+	MaxAgeDays int
+	Compress   bool
+}
+
+func newOutputHandler(cfg OutputConfig) (slog.Handler, io.Closer, error) {
+	outputLevel := slog.Level(minLevel)
+
+	if cfg.Level != "" {
+		lvl, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		outputLevel = lvl
+	}
+
+	w, closer := openOutput(cfg)
+
+	h, err := newFormatHandler(w, cfg.Format, outputLevel)
+	if err != nil {
+		return nil, nil, errors.Join(err, closer.Close())
+	}
+
+	return h, closer, nil
+}
+
+func openOutput(cfg OutputConfig) (io.Writer, io.Closer) {
+	switch cfg.Path {
+	case "", OutputStdout:
+		return os.Stdout, nopCloser{}
+	case OutputStderr:
+		return os.Stderr, nopCloser{}
+	}
+
+	w := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+
+	return w, w
+}
+
+// nopCloser не дает закрыть stdout и stderr вместе с логгером
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func newFormatHandler(
+	w io.Writer,
+	format string,
+	lvl slog.Level,
+) (slog.Handler, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl}), nil
+	case FormatText:
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: lvl}), nil
+	case FormatLogfmt:
+		return slog.NewTextHandler(
+			w,
+			&slog.HandlerOptions{
+				Level:       lvl,
+				ReplaceAttr: logfmtAttr,
+			},
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// logfmtAttr приводит встроенные атрибуты к принятым в logfmt именам
+func logfmtAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "ts"
+	case slog.LevelKey:
+		attr.Value = slog.StringValue(strings.ToLower(attr.Value.String()))
+	}
+
+	return attr
+}
+
+// fanoutHandler передает запись во все выводы, уровень которых
+// позволяет ее принять
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, lvl) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, rec.Level) {
+			errs = append(errs, handler.Handle(ctx, rec.Clone()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+
+	return &fanoutHandler{handlers: handlers}
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+
+	return &fanoutHandler{handlers: handlers}
+}
@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+var (
+	DefaultRedactKeys = []string{
+		"authorization",
+		"proxy-authorization",
+		"cookie",
+		"set-cookie",
+		"x-api-key",
+		"password",
+		"secret",
+		"secret_key",
+		"access_key",
+	}
+
+	// ключи конкретных полей, а не общие вроде name: общие ключи
+	// скрыли бы имена фаз, логгеров и целей SLO
+	DefaultHashKeys = []string{
+		"email",
+		"user_name",
+		"payload",
+		"object_key",
+	}
+)
+
+type RedactConfig struct {
+	// RedactKeys - ключи атрибутов, значения которых заменяются заглушкой
+	RedactKeys []string
+	// HashKeys - ключи атрибутов, значения которых заменяются хешем,
+	// чтобы записи об одном и том же значении можно было сопоставить
+	HashKeys []string
+	// HashSalt усложняет подбор исходных значений по хешу
+	HashSalt string
+}
+
+type redactMode int
+
+const (
+	modeRedact redactMode = iota + 1
+	modeHash
+)
+
+// redactHandler скрывает значения атрибутов с чувствительными ключами,
+// в том числе вложенных в группы; ключи сравниваются без учета регистра
+type redactHandler struct {
+	next slog.Handler
+	keys map[string]redactMode
+	salt string
+}
+
+func newRedactHandler(next slog.Handler, cfg RedactConfig) slog.Handler {
+	if len(cfg.RedactKeys) == 0 && len(cfg.HashKeys) == 0 {
+		return next
+	}
+
+	keys := make(map[string]redactMode, len(cfg.RedactKeys)+len(cfg.HashKeys))
+
+	for _, key := range cfg.HashKeys {
+		keys[strings.ToLower(key)] = modeHash
+	}
+
+	for _, key := range cfg.RedactKeys {
+		keys[strings.ToLower(key)] = modeRedact
+	}
+
+	return &redactHandler{
+		next: next,
+		keys: keys,
+		salt: cfg.HashSalt,
+	}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return h.next.Enabled(ctx, lvl)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	redacted := slog.NewRecord(rec.Time, rec.Level, rec.Message, rec.PC)
+
+	rec.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redact(attr))
+
+		return true
+	})
+
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+
+	for _, attr := range attrs {
+		redacted = append(redacted, h.redact(attr))
+	}
+
+	return &redactHandler{
+		next: h.next.WithAttrs(redacted),
+		keys: h.keys,
+		salt: h.salt,
+	}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{
+		next: h.next.WithGroup(name),
+		keys: h.keys,
+		salt: h.salt,
+	}
+}
+
+func (h *redactHandler) redact(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+
+	switch h.keys[strings.ToLower(attr.Key)] {
+	case modeRedact:
+		return slog.String(attr.Key, redactedValue)
+	case modeHash:
+		return slog.String(attr.Key, h.hash(attr.Value.String()))
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		return attr
+	}
+
+	group := attr.Value.Group()
+	redacted := make([]slog.Attr, 0, len(group))
+
+	for _, a := range group {
+		redacted = append(redacted, h.redact(a))
+	}
+
+	return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+}
+
+func (h *redactHandler) hash(value string) string {
+	sum := sha256.Sum256([]byte(h.salt + value))
+
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
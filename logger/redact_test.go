@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testRedact = RedactConfig{
+	RedactKeys: DefaultRedactKeys,
+	HashKeys:   DefaultHashKeys,
+	HashSalt:   "salt",
+}
+
+var hashPattern = regexp.MustCompile(`^sha256:[0-9a-f]{16}$`)
+
+type secretValuer string
+
+func (s secretValuer) LogValue() slog.Value {
+	return slog.StringValue(string(s))
+}
+
+func TestRedactMasksRedactKeys(t *testing.T) {
+	log, _, buf := newTestLogger(t, slog.LevelInfo, nil, testRedact)
+
+	log.With(slog.String("secret_key", "s3cr3t")).Info(
+		"msg",
+		slog.String("Authorization", "Bearer token"),
+		slog.String("password", "hunter2"),
+		slog.Any("access_key", secretValuer("AKIA")),
+		slog.String("user_id", "42"),
+	)
+
+	line := buf.String()
+	for _, raw := range []string{"s3cr3t", "Bearer token", "hunter2", "AKIA"} {
+		if strings.Contains(line, raw) {
+			t.Fatalf("%q leaks %q", line, raw)
+		}
+	}
+
+	rec := records(t, buf)[0]
+
+	for _, key := range []string{
+		"secret_key", "Authorization", "password", "access_key",
+	} {
+		if rec[key] != redactedValue {
+			t.Errorf("%s = %v, want %s", key, rec[key], redactedValue)
+		}
+	}
+
+	if rec["user_id"] != "42" {
+		t.Errorf("user_id = %v, want it untouched", rec["user_id"])
+	}
+}
+
+func TestRedactHashesHashKeys(t *testing.T) {
+	log, _, buf := newTestLogger(t, slog.LevelInfo, nil, testRedact)
+
+	log.Info("first", slog.String("email", "a@example.com"))
+	log.Info("second", slog.String("EMAIL", "a@example.com"))
+	log.Info("other", slog.String("email", "b@example.com"))
+	log.Info("key", slog.String("object_key", "user:1"))
+
+	recs := records(t, buf)
+
+	first, _ := recs[0]["email"].(string)
+	if !hashPattern.MatchString(first) {
+		t.Fatalf("email = %q, want sha256 hash", first)
+	}
+
+	if recs[1]["EMAIL"] != first {
+		t.Fatalf("hash is not stable: %v != %v", recs[1]["EMAIL"], first)
+	}
+
+	if recs[2]["email"] == first {
+		t.Fatal("different values must have different hashes")
+	}
+
+	if key, _ := recs[3]["object_key"].(string); !hashPattern.MatchString(key) {
+		t.Fatalf("object_key = %q, want sha256 hash", key)
+	}
+
+	unsalted := &redactHandler{}
+	if unsalted.hash("a@example.com") == first {
+		t.Fatal("hash must depend on the salt")
+	}
+}
+
+func TestRedactNestedGroups(t *testing.T) {
+	log, _, buf := newTestLogger(t, slog.LevelInfo, nil, testRedact)
+
+	log.WithGroup("request").
+		With(slog.String("cookie", "session=1")).
+		Info(
+			"msg",
+			slog.Group(
+				"user",
+				slog.String("email", "a@example.com"),
+				slog.Group("auth", slog.String("password", "hunter2")),
+			),
+		)
+
+	line := buf.String()
+	for _, raw := range []string{"session=1", "a@example.com", "hunter2"} {
+		if strings.Contains(line, raw) {
+			t.Fatalf("%q leaks %q", line, raw)
+		}
+	}
+
+	request, _ := records(t, buf)[0]["request"].(map[string]any)
+	user, _ := request["user"].(map[string]any)
+	auth, _ := user["auth"].(map[string]any)
+
+	if request["cookie"] != redactedValue || auth["password"] != redactedValue {
+		t.Fatalf("request = %v, want cookie and password redacted", request)
+	}
+
+	if email, _ := user["email"].(string); !hashPattern.MatchString(email) {
+		t.Fatalf("user.email = %q, want sha256 hash", email)
+	}
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SamplingRule ограничивает частоту записей с одинаковым сообщением:
+// за каждый Interval пишутся первые First записей, а дальше
+// только каждая Thereafter-я
+type SamplingRule struct {
+	Message    string
+	First      int
+	Thereafter int
+	Interval   time.Duration
+}
+
+// ParseSamplingRules разбирает правила в формате
+// message=first/thereafter, разделенные точкой с запятой
+func ParseSamplingRules(s string, interval time.Duration) ([]SamplingRule, error) {
+	var rules []SamplingRule
+
+	for item := range strings.SplitSeq(s, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		msg, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("sampling rule %q: missing '='", item)
+		}
+
+		firstStr, thereafterStr, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf(
+				"sampling rule %q: expected first/thereafter",
+				item,
+			)
+		}
+
+		first, err := strconv.Atoi(strings.TrimSpace(firstStr))
+		if err != nil {
+			return nil, fmt.Errorf("sampling rule %q: %w", item, err)
+		}
+
+		thereafter, err := strconv.Atoi(strings.TrimSpace(thereafterStr))
+		if err != nil {
+			return nil, fmt.Errorf("sampling rule %q: %w", item, err)
+		}
+
+		rules = append(rules, SamplingRule{
+			Message:    strings.TrimSpace(msg),
+			First:      first,
+			Thereafter: thereafter,
+			Interval:   interval,
+		})
+	}
+
+	return rules, nil
+}
+
+type samplingCounter struct {
+	rule SamplingRule
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+func (c *samplingCounter) allow(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.windowStart) >= c.rule.Interval {
+		c.windowStart = now
+		c.count = 0
+	}
+
+	c.count++
+
+	if c.count <= c.rule.First {
+		return true
+	}
+
+	if c.rule.Thereafter <= 0 {
+		return false
+	}
+
+	return (c.count-c.rule.First)%c.rule.Thereafter == 0
+}
+
+// samplingHandler прореживает записи, для сообщений которых
+// заданы правила; записи уровня ошибки не прореживаются
+type samplingHandler struct {
+	next     slog.Handler
+	counters map[string]*samplingCounter
+}
+
+func newSamplingHandler(next slog.Handler, rules []SamplingRule) slog.Handler {
+	if len(rules) == 0 {
+		return next
+	}
+
+	counters := make(map[string]*samplingCounter, len(rules))
+
+	for _, rule := range rules {
+		counters[rule.Message] = &samplingCounter{rule: rule}
+	}
+
+	return &samplingHandler{
+		next:     next,
+		counters: counters,
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return h.next.Enabled(ctx, lvl)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	counter, ok := h.counters[rec.Message]
+	if ok && rec.Level < slog.LevelError && !counter.allow(rec.Time) {
+		return nil
+	}
+
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{
+		next:     h.next.WithAttrs(attrs),
+		counters: h.counters,
+	}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{
+		next:     h.next.WithGroup(name),
+		counters: h.counters,
+	}
+}
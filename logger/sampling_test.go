@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSamplingNeverDropsErrors(t *testing.T) {
+	log, _, buf := newTestLogger(
+		t,
+		slog.LevelInfo,
+		[]SamplingRule{{
+			Message:    "noisy",
+			First:      1,
+			Thereafter: 0,
+			Interval:   time.Hour,
+		}},
+		RedactConfig{},
+	)
+
+	for range 3 {
+		log.Info("noisy")
+	}
+
+	for range 3 {
+		log.Error("noisy")
+	}
+
+	log.Info("other")
+
+	levels := map[string]int{}
+	for _, rec := range records(t, buf) {
+		levels[rec["level"].(string)+" "+rec["msg"].(string)]++
+	}
+
+	want := map[string]int{
+		"INFO noisy":  1,
+		"ERROR noisy": 3,
+		"INFO other":  1,
+	}
+
+	for key, n := range want {
+		if levels[key] != n {
+			t.Fatalf("written records = %v, want %v", levels, want)
+		}
+	}
+}
+
+func TestSamplingThereafter(t *testing.T) {
+	c := &samplingCounter{rule: SamplingRule{
+		First:      2,
+		Thereafter: 3,
+		Interval:   time.Minute,
+	}}
+
+	now := time.Now()
+
+	var allowed []int
+
+	for i := 1; i <= 8; i++ {
+		if c.allow(now) {
+			allowed = append(allowed, i)
+		}
+	}
+
+	// после первых двух пропускается каждая третья запись
+	want := []int{1, 2, 5, 8}
+	if !slices.Equal(allowed, want) {
+		t.Fatalf("allowed = %v, want %v", allowed, want)
+	}
+
+	// новое окно снова пропускает первые записи
+	if !c.allow(now.Add(time.Minute)) {
+		t.Fatal("first record of a new window was dropped")
+	}
+}
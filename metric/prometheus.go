@@ -4,45 +4,210 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
-	TotalRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "endpoint", "status"},
-	)
+type Metrics struct {
+	TotalRequests   *prometheus.CounterVec
+	RequestDuration *prometheus.HistogramVec
+	// ActiveRequests - счетчик активных запросов
+	ActiveRequests prometheus.Gauge
+	// ErrorsTotal - счетчик ошибок
+	ErrorsTotal *prometheus.CounterVec
+	// QueuedRequests - запросы, ожидающие свободного слота
+	QueuedRequests prometheus.Gauge
+	// ShedRequestsTotal - счетчик отброшенных из-за перегрузки запросов
+	ShedRequestsTotal *prometheus.CounterVec
+	// RequestValidationFailuresTotal - нарушения схемы OpenAPI
+	// в запросах по месту: body, path, query или header
+	RequestValidationFailuresTotal *prometheus.CounterVec
+	// ResponseValidationFailuresTotal - ответы, расходящиеся
+	// с документом OpenAPI; считаются только при проверке ответов
+	ResponseValidationFailuresTotal *prometheus.CounterVec
 
-	RequestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Request duration in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "endpoint"},
-	)
+	StorageOperationsTotal    *prometheus.CounterVec
+	StorageOperationDuration  *prometheus.HistogramVec
+	StorageObjectSize         *prometheus.HistogramVec
+	StorageListedObjectsTotal *prometheus.CounterVec
 
-	// ActiveRequests - счетчик активных запросов
-	ActiveRequests = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "http_active_requests",
-			Help: "Number of active HTTP requests",
-		},
-	)
+	GRPCRequestsTotal   *prometheus.CounterVec
+	GRPCRequestDuration *prometheus.HistogramVec
+	// GRPCActiveStreams - открытые серверные потоки, например WatchUsers
+	GRPCActiveStreams *prometheus.GaugeVec
 
-	// ErrorsTotal - счетчик ошибок
-	ErrorsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_errors_total",
-			Help: "Total number of HTTP errors",
-		},
-		[]string{"method", "endpoint", "error_type"},
+	UserOperationsTotal         *prometheus.CounterVec
+	UserValidationFailuresTotal *prometheus.CounterVec
+	// UserEventsDroppedTotal - события, не доставленные медленным
+	// подписчикам WatchUsers
+	UserEventsDroppedTotal prometheus.Counter
+	NotificationsTotal     *prometheus.CounterVec
+	UsersTotal             prometheus.Gauge
+}
+
+// New создает метрики и регистрирует их в переданном реестре
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		TotalRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests",
+			},
+			[]string{"method", "endpoint", "status"},
+		),
+		RequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Request duration in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "endpoint"},
+		),
+		ActiveRequests: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_active_requests",
+				Help: "Number of active HTTP requests",
+			},
+		),
+		ErrorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_errors_total",
+				Help: "Total number of HTTP errors",
+			},
+			[]string{"method", "endpoint", "error_type"},
+		),
+		QueuedRequests: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_queued_requests",
+				Help: "Number of HTTP requests waiting for a concurrency slot",
+			},
+		),
+		ShedRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_shed_requests_total",
+				Help: "Total number of HTTP requests rejected by load shedding",
+			},
+			[]string{"method", "reason"},
+		),
+		RequestValidationFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_request_validation_failures_total",
+				Help: "Total number of OpenAPI request schema violations",
+			},
+			[]string{"method", "endpoint", "in"},
+		),
+		ResponseValidationFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_response_validation_failures_total",
+				Help: "Total number of responses not matching OpenAPI document",
+			},
+			[]string{"method", "endpoint", "status"},
+		),
+		StorageOperationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "storage_operations_total",
+				Help: "Total number of storage operations",
+			},
+			[]string{"storage", "operation", "outcome", "error_class"},
+		),
+		StorageOperationDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "storage_operation_duration_seconds",
+				Help:    "Storage operation duration in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"storage", "operation", "outcome"},
+		),
+		StorageObjectSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "storage_object_size_bytes",
+				Help:    "Size of objects written to and read from storage",
+				Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+			},
+			[]string{"storage", "operation"},
+		),
+		StorageListedObjectsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "storage_listed_objects_total",
+				Help: "Total number of objects scanned by list operations",
+			},
+			[]string{"storage"},
+		),
+		UserOperationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "user_operations_total",
+				Help: "Total number of successful user mutations",
+			},
+			[]string{"operation"},
+		),
+		UserValidationFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "user_validation_failures_total",
+				Help: "Total number of rejected user payloads by field",
+			},
+			[]string{"field"},
+		),
+		GRPCRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_requests_total",
+				Help: "Total number of gRPC requests by status code",
+			},
+			[]string{"method", "code"},
+		),
+		GRPCRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "grpc_request_duration_seconds",
+				Help:    "gRPC request duration in seconds",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method"},
+		),
+		GRPCActiveStreams: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "grpc_active_streams",
+				Help: "Number of open gRPC server streams",
+			},
+			[]string{"method"},
+		),
+		UserEventsDroppedTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "user_events_dropped_total",
+				Help: "Total number of user events dropped for slow subscribers",
+			},
+		),
+		NotificationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "notifications_total",
+				Help: "Total number of notifications by outcome",
+			},
+			[]string{"message", "outcome"},
+		),
+		UsersTotal: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "users_total",
+				Help: "Total number of users in storage",
+			},
+		),
+	}
+
+	reg.MustRegister(
+		m.TotalRequests,
+		m.RequestDuration,
+		m.ActiveRequests,
+		m.ErrorsTotal,
+		m.QueuedRequests,
+		m.ShedRequestsTotal,
+		m.RequestValidationFailuresTotal,
+		m.ResponseValidationFailuresTotal,
+		m.StorageOperationsTotal,
+		m.StorageOperationDuration,
+		m.StorageObjectSize,
+		m.StorageListedObjectsTotal,
+		m.GRPCRequestsTotal,
+		m.GRPCRequestDuration,
+		m.GRPCActiveStreams,
+		m.UserOperationsTotal,
+		m.UserValidationFailuresTotal,
+		m.UserEventsDroppedTotal,
+		m.NotificationsTotal,
+		m.UsersTotal,
 	)
-)
 
-func init() {
-	prometheus.MustRegister(TotalRequests)
-	prometheus.MustRegister(RequestDuration)
-	prometheus.MustRegister(ActiveRequests)
-	prometheus.MustRegister(ErrorsTotal)
+	return m
 }
@@ -0,0 +1,44 @@
+package metric
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewRegistersAllMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	v := reflect.ValueOf(m).Elem()
+
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+
+		c, ok := v.Field(i).Interface().(prometheus.Collector)
+		if !ok || v.Field(i).IsNil() {
+			t.Errorf("%s is not a collector", name)
+
+			continue
+		}
+
+		var are prometheus.AlreadyRegisteredError
+		if err := reg.Register(c); !errors.As(err, &are) {
+			t.Errorf("%s is not registered in the registry: %v", name, err)
+		}
+	}
+}
+
+func TestNewIsolatesRegistries(t *testing.T) {
+	first := New(prometheus.NewRegistry())
+	second := New(prometheus.NewRegistry())
+
+	first.UsersTotal.Set(3)
+
+	if got := testutil.ToFloat64(second.UsersTotal); got != 0 {
+		t.Fatalf("second UsersTotal = %v, want 0", got)
+	}
+}
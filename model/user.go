@@ -1,7 +1,50 @@
 package model
 
+import (
+	"log/slog"
+	"net/mail"
+)
+
+// User - теги openapi описывают поля в документе OpenAPI
 type User struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID    string `json:"id" openapi:"format=uuid"`
+	Name  string `json:"name" openapi:"minLength=1"`
+	Email string `json:"email" openapi:"format=email"`
+}
+
+// LogValue оставляет в логах только идентификатор,
+// чтобы имя и почта пользователя не попадали в них случайно
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", u.ID))
+}
+
+// ValidationError - некорректное значение поля, пришедшее от клиента
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate проверяет поля, которые задает клиент, и возвращает
+// *ValidationError для первого некорректного
+func (u User) Validate() error {
+	if u.Name == "" {
+		return &ValidationError{Field: "name", Message: "name is required"}
+	}
+
+	if u.Email == "" {
+		return &ValidationError{Field: "email", Message: "email is required"}
+	}
+
+	if _, err := mail.ParseAddress(u.Email); err != nil {
+		return &ValidationError{
+			Field:   "email",
+			Message: "email not valid: " + err.Error(),
+		}
+	}
+
+	return nil
 }
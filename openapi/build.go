@@ -0,0 +1,265 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const ContentTypeJSON = "application/json"
+
+// Operations - описания операций по ключу "METHOD /path",
+// например "GET /api/users/{id}", как маршруты в целях SLO
+type Operations map[string]*Operation
+
+// JSONBody описывает обязательное JSON тело запроса типа T
+func JSONBody[T any]() *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content: map[string]*MediaType{
+			ContentTypeJSON: {goTypes: []reflect.Type{reflect.TypeFor[T]()}},
+		},
+	}
+}
+
+// JSONResponse описывает ответ с JSON телом типа T
+func JSONResponse[T any](description string) *Response {
+	return ContentResponse[T](description, ContentTypeJSON)
+}
+
+// ContentResponse описывает ответ с телом типа T и заданным
+// типом содержимого
+func ContentResponse[T any](description, contentType string) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]*MediaType{
+			contentType: {goTypes: []reflect.Type{reflect.TypeFor[T]()}},
+		},
+	}
+}
+
+// SchemaResponse описывает ответ, схема которого задана явно,
+// а не выводится из типа
+func SchemaResponse(description, contentType string, s *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]*MediaType{
+			contentType: {Schema: s},
+		},
+	}
+}
+
+// EmptyResponse описывает ответ без тела
+func EmptyResponse(description string) *Response {
+	return &Response{Description: description}
+}
+
+func PathParam(name, description string, s *Schema) *Parameter {
+	return &Parameter{
+		Name:        name,
+		In:          InPath,
+		Description: description,
+		Required:    true,
+		Schema:      s,
+	}
+}
+
+func QueryParam(name, description string, s *Schema) *Parameter {
+	return &Parameter{
+		Name:        name,
+		In:          InQuery,
+		Description: description,
+		Schema:      s,
+	}
+}
+
+func HeaderParam(name, description string, s *Schema) *Parameter {
+	return &Parameter{
+		Name:        name,
+		In:          InHeader,
+		Description: description,
+		Schema:      s,
+	}
+}
+
+// Build обходит маршруты router и собирает документ из описаний ops.
+// Возвращает ошибку, если маршрут не описан, описание не соответствует
+// ни одному маршруту или параметры пути расходятся с шаблоном, поэтому
+// новый маршрут нельзя добавить, не описав его.
+func Build(info Info, router *mux.Router, ops Operations) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	gen := newSchemaGenerator()
+	documented := make(map[string]bool, len(ops))
+
+	var errs []error
+
+	err := router.Walk(func(
+		route *mux.Route,
+		_ *mux.Router,
+		_ []*mux.Route,
+	) error {
+		// маршрут с подроутером сам запросы не обслуживает
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			errs = append(errs, err)
+
+			return nil
+		}
+
+		path := PathFromTemplate(tpl)
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: route has no methods", path))
+
+			return nil
+		}
+
+		for _, method := range methods {
+			key := OperationKey(method, path)
+
+			op, ok := ops[key]
+			if !ok {
+				errs = append(
+					errs,
+					fmt.Errorf("%s: route is not documented", key),
+				)
+
+				continue
+			}
+
+			documented[key] = true
+			errs = append(errs, checkPathParams(key, path, op))
+
+			gen.resolveOperation(op)
+
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(PathItem)
+			}
+
+			doc.Paths[path][strings.ToLower(method)] = op
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking routes: %w", err)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(ops)) {
+		if !documented[key] {
+			errs = append(errs, fmt.Errorf("%s: operation has no route", key))
+		}
+	}
+
+	errs = append(errs, gen.errs...)
+
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	doc.Components.Schemas = gen.schemas
+
+	return doc, nil
+}
+
+func (g *schemaGenerator) resolveOperation(op *Operation) {
+	if op.RequestBody != nil {
+		g.resolveContent(op.RequestBody.Content)
+	}
+
+	for _, resp := range op.Responses {
+		g.resolveContent(resp.Content)
+	}
+}
+
+func (g *schemaGenerator) resolveContent(content map[string]*MediaType) {
+	for _, media := range content {
+		if media.Schema != nil {
+			continue
+		}
+
+		switch len(media.goTypes) {
+		case 0:
+		case 1:
+			media.Schema = g.schemaFor(media.goTypes[0])
+		default:
+			media.Schema = &Schema{}
+			for _, t := range media.goTypes {
+				media.Schema.AnyOf = append(media.Schema.AnyOf, g.schemaFor(t))
+			}
+		}
+	}
+}
+
+func checkPathParams(key, path string, op *Operation) error {
+	var (
+		errs     []error
+		declared = make(map[string]bool)
+	)
+
+	for _, p := range op.Parameters {
+		if p.In == InPath {
+			declared[p.Name] = true
+		}
+	}
+
+	for _, name := range PathParams(path) {
+		if !declared[name] {
+			errs = append(errs, fmt.Errorf(
+				"%s: path parameter %s is not documented",
+				key, name,
+			))
+		}
+
+		delete(declared, name)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(declared)) {
+		errs = append(errs, fmt.Errorf(
+			"%s: documented path parameter %s is not in path",
+			key, name,
+		))
+	}
+
+	return errors.Join(errs...)
+}
+
+var pathVarRe = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// PathFromTemplate убирает из шаблона маршрута mux регулярные
+// выражения переменных: /users/{id:[0-9]+} становится /users/{id}
+func PathFromTemplate(tpl string) string {
+	return pathVarRe.ReplaceAllString(tpl, "{$1}")
+}
+
+// PathParams возвращает имена переменных пути в порядке появления
+func PathParams(path string) []string {
+	var names []string
+
+	for _, m := range pathVarRe.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+
+	return names
+}
+
+// OperationKey возвращает ключ операции в Operations
+func OperationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Version - версия спецификации OpenAPI, которой соответствует Document
+const Version = "3.1.0"
+
+// Document - подмножество OpenAPI 3.1, которого хватает для
+// описания HTTP API сервиса
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem - операции одного пути по методам в нижнем регистре
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+
+	// goTypes - типы Go, из которых Build выводит Schema;
+	// несколько типов дают anyOf
+	goTypes []reflect.Type
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema - подмножество JSON Schema 2020-12, которое используется
+// в OpenAPI 3.1
+type Schema struct {
+	Ref         string `json:"$ref,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Nullable добавляет null к типу: в 3.1 нет ключа nullable,
+	// вместо него type становится массивом
+	Nullable bool `json:"-"`
+
+	Enum      []any    `json:"enum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+
+	out := struct {
+		*plain
+		Type any `json:"type,omitempty"`
+	}{plain: (*plain)(s)}
+
+	switch {
+	case s.Type == "":
+	case s.Nullable:
+		out.Type = []string{s.Type, "null"}
+	default:
+		out.Type = s.Type
+	}
+
+	return json.Marshal(out)
+}
+
+// Operation ищет операцию по методу и пути в формате документа,
+// например /api/users/{id}
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Resolve возвращает схему, на которую ссылается $ref,
+// или саму схему, если это не ссылка
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[refName(s.Ref)]
+	}
+
+	return s
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+func schemaRef(name string) string {
+	return schemaRefPrefix + name
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, schemaRefPrefix)
+}
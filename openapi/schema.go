@@ -0,0 +1,248 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaGenerator выводит схемы из типов Go так, как их кодирует
+// encoding/json. Именованные структуры попадают в components
+// и подставляются ссылкой, поэтому рекурсивные типы не зацикливаются.
+//
+// Поля без omitempty считаются обязательными. Ограничения задаются
+// тегом openapi через запятую: format, minLength, maxLength,
+// minimum, maximum, pattern и enum со значениями через |, например
+// `openapi:"minLength=1,format=email"`.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+	errs    []error
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		types:   make(map[string]reflect.Type),
+	}
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType):
+		// произвольный JSON, схему по типу не вывести
+		return &Schema{}
+	case t.Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+
+		// nil слайс кодируется как null
+		return &Schema{
+			Type:     "array",
+			Items:    g.schemaFor(t.Elem()),
+			Nullable: true,
+		}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: g.schemaFor(t.Elem()),
+			Nullable:             true,
+		}
+	case reflect.Pointer:
+		s := g.schemaFor(t.Elem())
+		// к ссылке null не добавить без anyOf, поэтому
+		// указатели на структуры описываются как сами структуры
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return g.namedStructSchema(t)
+	case reflect.Interface:
+		return &Schema{}
+	default:
+		g.errs = append(g.errs, fmt.Errorf("unsupported type %s", t))
+
+		return &Schema{}
+	}
+}
+
+func (g *schemaGenerator) namedStructSchema(t reflect.Type) *Schema {
+	name := t.Name()
+
+	known, ok := g.types[name]
+	switch {
+	case ok && known != t:
+		g.errs = append(g.errs, fmt.Errorf(
+			"schema name %s is used by both %s and %s",
+			name, known, t,
+		))
+	case !ok:
+		g.types[name] = t
+		// место занимается до обхода полей, чтобы рекурсивная
+		// ссылка на этот же тип не ушла в бесконечный обход
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t)
+	}
+
+	return &Schema{Ref: schemaRef(name)}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	g.addFields(s, t)
+
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		// поля встроенной структуры без имени в теге json
+		// поднимаются на уровень выше
+		if field.Anonymous && name == "" &&
+			fieldType.Kind() == reflect.Struct {
+			g.addFields(s, fieldType)
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.schemaFor(field.Type)
+
+		err := applyTag(prop, field.Tag.Get("openapi"))
+		if err != nil {
+			g.errs = append(g.errs, fmt.Errorf(
+				"%s.%s: %w",
+				t, field.Name, err,
+			))
+		}
+
+		s.Properties[name] = prop
+
+		if !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(opts, option string) bool {
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func applyTag(s *Schema, tag string) error {
+	if tag == "" {
+		return nil
+	}
+
+	for item := range strings.SplitSeq(tag, ",") {
+		key, value, _ := strings.Cut(item, "=")
+
+		switch key {
+		case "format":
+			s.Format = value
+		case "pattern":
+			if _, err := compilePattern(value); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+
+			s.Pattern = value
+		case "enum":
+			for v := range strings.SplitSeq(value, "|") {
+				s.Enum = append(s.Enum, v)
+			}
+		case "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+
+			if key == "minLength" {
+				s.MinLength = &n
+			} else {
+				s.MaxLength = &n
+			}
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+
+			if key == "minimum" {
+				s.Minimum = &n
+			} else {
+				s.Maximum = &n
+			}
+		default:
+			return fmt.Errorf("unknown openapi tag key %q", key)
+		}
+	}
+
+	return nil
+}
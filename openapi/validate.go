@@ -0,0 +1,387 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Violation - одно нарушение схемы. Для тела Pointer содержит
+// JSON Pointer (RFC 6901) на поле, для параметров - Parameter и In.
+type Violation struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	In        string `json:"in,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+// ValidateJSON проверяет значение, разобранное json.Decoder
+// с UseNumber, и возвращает все нарушения, а не первое
+func (d *Document) ValidateJSON(s *Schema, value any) []Violation {
+	var violations []Violation
+
+	d.validate(s, value, "", func(pointer, detail string) {
+		violations = append(violations, Violation{
+			Pointer: pointer,
+			Detail:  detail,
+		})
+	})
+
+	return violations
+}
+
+// ValidateParameter проверяет строковое значение параметра пути,
+// запроса или заголовка; present - был ли параметр передан
+func (d *Document) ValidateParameter(
+	p *Parameter,
+	raw string,
+	present bool,
+) []Violation {
+	var violations []Violation
+
+	report := func(_, detail string) {
+		violations = append(violations, Violation{
+			Parameter: p.Name,
+			In:        p.In,
+			Detail:    detail,
+		})
+	}
+
+	if !present {
+		if p.Required {
+			report("", "parameter is required")
+		}
+
+		return violations
+	}
+
+	value, err := parseParameter(d.Resolve(p.Schema), raw)
+	if err != nil {
+		report("", err.Error())
+
+		return violations
+	}
+
+	d.validate(p.Schema, value, "", report)
+
+	return violations
+}
+
+// parseParameter приводит строку к типу схемы, чтобы проверять
+// параметры теми же правилами, что и JSON
+func parseParameter(s *Schema, raw string) (any, error) {
+	if s == nil {
+		return raw, nil
+	}
+
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("must be a %s", s.Type)
+		}
+
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+type reportFunc func(pointer, detail string)
+
+func (d *Document) validate(
+	s *Schema,
+	value any,
+	pointer string,
+	report reportFunc,
+) {
+	s = d.Resolve(s)
+	if s == nil {
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		d.validateAnyOf(s.AnyOf, value, pointer, report)
+
+		return
+	}
+
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			report(pointer, "must be "+article(s.Type)+", got null")
+		}
+
+		return
+	}
+
+	if s.Type != "" && !matchesType(s.Type, value) {
+		report(pointer, fmt.Sprintf(
+			"must be %s, got %s",
+			article(s.Type), jsonType(value),
+		))
+
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool {
+		return fmt.Sprint(e) == fmt.Sprint(value)
+	}) {
+		report(pointer, fmt.Sprintf("must be one of %v", s.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		validateString(s, v, pointer, report)
+	case json.Number:
+		validateNumber(s, v, pointer, report)
+	case []any:
+		for i, item := range v {
+			d.validate(s.Items, item, pointer+"/"+strconv.Itoa(i), report)
+		}
+	case map[string]any:
+		d.validateObject(s, v, pointer, report)
+	}
+}
+
+func (d *Document) validateAnyOf(
+	schemas []*Schema,
+	value any,
+	pointer string,
+	report reportFunc,
+) {
+	for _, s := range schemas {
+		valid := true
+
+		d.validate(s, value, pointer, func(string, string) {
+			valid = false
+		})
+
+		if valid {
+			return
+		}
+	}
+
+	report(pointer, "must match at least one of the allowed schemas")
+}
+
+func (d *Document) validateObject(
+	s *Schema,
+	obj map[string]any,
+	pointer string,
+	report reportFunc,
+) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			report(pointer+"/"+escapePointer(name), "is required")
+		}
+	}
+
+	// порядок ключей фиксирован, чтобы нарушения шли в одном
+	// и том же порядке
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		propPointer := pointer + "/" + escapePointer(key)
+
+		if prop, ok := s.Properties[key]; ok {
+			d.validate(prop, obj[key], propPointer, report)
+
+			continue
+		}
+
+		if s.AdditionalProperties != nil {
+			d.validate(
+				s.AdditionalProperties,
+				obj[key],
+				propPointer,
+				report,
+			)
+		}
+	}
+}
+
+func validateString(
+	s *Schema,
+	v string,
+	pointer string,
+	report reportFunc,
+) {
+	length := utf8.RuneCountInString(v)
+
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			report(pointer, "must not be empty")
+		} else {
+			report(pointer, fmt.Sprintf(
+				"must be at least %d characters long",
+				*s.MinLength,
+			))
+		}
+	}
+
+	if s.MaxLength != nil && length > *s.MaxLength {
+		report(pointer, fmt.Sprintf(
+			"must be at most %d characters long",
+			*s.MaxLength,
+		))
+	}
+
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		if err == nil && !re.MatchString(v) {
+			report(pointer, "must match pattern "+s.Pattern)
+		}
+	}
+
+	if err := checkFormat(s.Format, v); err != nil {
+		report(pointer, err.Error())
+	}
+}
+
+func validateNumber(
+	s *Schema,
+	v json.Number,
+	pointer string,
+	report reportFunc,
+) {
+	f, err := v.Float64()
+	if err != nil {
+		report(pointer, "must be a number")
+
+		return
+	}
+
+	if s.Minimum != nil && f < *s.Minimum {
+		report(pointer, fmt.Sprintf("must be at least %v", *s.Minimum))
+	}
+
+	if s.Maximum != nil && f > *s.Maximum {
+		report(pointer, fmt.Sprintf("must be at most %v", *s.Maximum))
+	}
+
+	switch s.Format {
+	case "int32":
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			report(pointer, "must fit into int32")
+		}
+	case "int64":
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err != nil {
+			report(pointer, "must fit into int64")
+		}
+	}
+}
+
+// checkFormat проверяет только форматы, которые использует
+// документ; незнакомые форматы, как и требует JSON Schema,
+// считаются аннотацией
+func checkFormat(format, v string) error {
+	switch format {
+	case "email":
+		if _, err := mail.ParseAddress(v); err != nil {
+			return errors.New("must be a valid email address")
+		}
+	case "uuid":
+		if _, err := uuid.Parse(v); err != nil {
+			return errors.New("must be a valid UUID")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return errors.New("must be an RFC 3339 date-time")
+		}
+	}
+
+	return nil
+}
+
+func matchesType(typ string, value any) bool {
+	switch v := value.(type) {
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+
+		_, err := strconv.ParseInt(v.String(), 10, 64)
+
+		return typ == "integer" && err == nil
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	default:
+		return false
+	}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+func article(typ string) string {
+	switch typ {
+	case "array", "object", "integer":
+		return "an " + typ
+	default:
+		return "a " + typ
+	}
+}
+
+// escapePointer экранирует ключ для JSON Pointer по RFC 6901
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(pattern, re)
+
+	return re, nil
+}
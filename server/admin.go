@@ -0,0 +1,76 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/dzherb/mifi-go-microservice/health"
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/handler"
+	"github.com/dzherb/mifi-go-microservice/slo"
+)
+
+// AdminHandler обслуживает служебные эндпоинты, которые не должны
+// быть доступны снаружи: метрики, пробы и профилировщик
+func AdminHandler(
+	log *slog.Logger,
+	gatherer prometheus.Gatherer,
+	checker *health.Checker,
+	sloTracker *slo.Tracker,
+	logLevels *logger.Levels,
+) http.Handler {
+	r := mux.NewRouter()
+
+	r.Handle(
+		"/metrics",
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+	).Methods(http.MethodGet)
+
+	healthHandler := handler.NewHealthHandler(log, checker)
+	r.Handle(
+		"/healthz",
+		http.HandlerFunc(healthHandler.Live),
+	).Methods(http.MethodGet)
+	r.Handle(
+		"/readyz",
+		http.HandlerFunc(healthHandler.Ready),
+	).Methods(http.MethodGet)
+
+	sloHandler := handler.NewSLOHandler(log, sloTracker)
+	r.Handle(
+		"/admin/slo",
+		http.HandlerFunc(sloHandler.Summary),
+	).Methods(http.MethodGet)
+
+	logLevelHandler := handler.NewLogLevelHandler(log, logLevels)
+	r.Handle(
+		"/admin/loglevel",
+		http.HandlerFunc(logLevelHandler.Get),
+	).Methods(http.MethodGet)
+	r.Handle(
+		"/admin/loglevel",
+		http.HandlerFunc(logLevelHandler.Update),
+	).Methods(http.MethodPut)
+
+	runtimeHandler := handler.NewRuntimeHandler(log)
+	r.Handle(
+		"/debug/runtime",
+		http.HandlerFunc(runtimeHandler.Info),
+	).Methods(http.MethodGet)
+
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+
+	r.NotFoundHandler = handler.NotFound(log)
+	r.MethodNotAllowedHandler = handler.MethodNotAllowed(log)
+
+	return r
+}
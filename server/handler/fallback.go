@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+// NotFound отвечает на запросы, для которых нет маршрута
+func NotFound(log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteProblem(
+			w, r, logger.FromContext(r.Context(), log),
+			response.NewProblem(
+				response.ProblemNotFound,
+				"no route for "+r.URL.Path,
+			),
+		)
+	})
+}
+
+// MethodNotAllowed отвечает на запросы к существующему пути
+// с методом, который путь не поддерживает
+func MethodNotAllowed(log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.WriteProblem(
+			w, r, logger.FromContext(r.Context(), log),
+			response.NewProblem(
+				response.ProblemMethodNotAllowed,
+				r.Method+" is not supported for "+r.URL.Path,
+			),
+		)
+	})
+}
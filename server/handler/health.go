@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/health"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+type Health struct {
+	Status string `json:"status"`
+}
+
+type HealthHandler struct {
+	log     *slog.Logger
+	checker *health.Checker
+}
+
+func NewHealthHandler(
+	log *slog.Logger,
+	checker *health.Checker,
+) *HealthHandler {
+	return &HealthHandler{
+		log:     log,
+		checker: checker,
+	}
+}
+
+func (h *HealthHandler) Live(w http.ResponseWriter, _ *http.Request) {
+	response.Write(w, h.log, Health{Status: health.StatusOK}, http.StatusOK)
+}
+
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Check(r.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	response.Write(w, h.log, report, status)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+type LogLevels interface {
+	Level() slog.Level
+	SetLevel(slog.Level)
+	SetLoggerLevel(string, slog.Level)
+	ResetLoggerLevel(string)
+	LoggerLevels() map[string]slog.Level
+}
+
+type LogLevelResponse struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers"`
+}
+
+type LogLevelUpdateRequest struct {
+	// Logger - имя дочернего логгера, пустое означает корневой
+	Logger string `json:"logger"`
+	// Level - новый уровень; пустой сбрасывает переопределение
+	// дочернего логгера
+	Level string `json:"level"`
+}
+
+type LogLevelHandler struct {
+	log    *slog.Logger
+	levels LogLevels
+}
+
+func NewLogLevelHandler(log *slog.Logger, levels LogLevels) *LogLevelHandler {
+	return &LogLevelHandler{
+		log:    log,
+		levels: levels,
+	}
+}
+
+func (h *LogLevelHandler) Get(w http.ResponseWriter, _ *http.Request) {
+	response.Write(w, h.log, h.currentLevels(), http.StatusOK)
+}
+
+func (h *LogLevelHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelUpdateRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		response.WriteProblem(
+			w, r, h.log,
+			response.NewProblem(response.ProblemMalformedBody, err.Error()),
+		)
+
+		return
+	}
+
+	if req.Level == "" {
+		if req.Logger == "" {
+			h.writeLevelError(w, r, "level is required")
+
+			return
+		}
+
+		h.levels.ResetLoggerLevel(req.Logger)
+		h.log.Info(
+			"log level reset",
+			slog.String("target", req.Logger),
+		)
+		response.Write(w, h.log, h.currentLevels(), http.StatusOK)
+
+		return
+	}
+
+	lvl, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		h.writeLevelError(w, r, err.Error())
+
+		return
+	}
+
+	if req.Logger == "" {
+		h.levels.SetLevel(lvl)
+	} else {
+		h.levels.SetLoggerLevel(req.Logger, lvl)
+	}
+
+	h.log.Info(
+		"log level changed",
+		slog.String("target", req.Logger),
+		slog.String("level", lvl.String()),
+	)
+
+	response.Write(w, h.log, h.currentLevels(), http.StatusOK)
+}
+
+func (h *LogLevelHandler) writeLevelError(
+	w http.ResponseWriter,
+	r *http.Request,
+	detail string,
+) {
+	response.WriteProblem(
+		w, r, h.log,
+		response.NewValidationProblem([]openapi.Violation{{
+			Pointer: "/level",
+			Detail:  detail,
+		}}),
+	)
+}
+
+func (h *LogLevelHandler) currentLevels() LogLevelResponse {
+	loggers := make(map[string]string)
+
+	for name, lvl := range h.levels.LoggerLevels() {
+		loggers[name] = lvl.String()
+	}
+
+	return LogLevelResponse{
+		Level:   h.levels.Level().String(),
+		Loggers: loggers,
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+)
+
+// docsPage - Swagger UI, который загружает спецификацию
+// с соседнего пути openapi.json; сами скрипты берутся из CDN
+//
+//go:embed static/docs.html
+var docsPage []byte
+
+type OpenAPIHandler struct {
+	log  *slog.Logger
+	spec []byte
+}
+
+func NewOpenAPIHandler(log *slog.Logger) *OpenAPIHandler {
+	return &OpenAPIHandler{log: log}
+}
+
+// SetDocument кодирует документ один раз; документ собирается
+// после регистрации всех маршрутов, включая маршруты этого
+// обработчика, поэтому задается отдельно от конструктора
+func (h *OpenAPIHandler) SetDocument(doc *openapi.Document) error {
+	spec, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding OpenAPI document: %w", err)
+	}
+
+	h.spec = spec
+
+	return nil
+}
+
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(h.spec)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Error(
+			"error writing response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (h *OpenAPIHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(docsPage)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Error(
+			"error writing response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
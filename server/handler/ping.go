@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/dzherb/mifi-go-microservice/logger"
 	"github.com/dzherb/mifi-go-microservice/server/response"
 )
 
@@ -27,5 +28,10 @@ func NewPingHandler(log *slog.Logger) *PingHandler {
 }
 
 func (h *PingHandler) Ping(w http.ResponseWriter, r *http.Request) {
-	response.Write(w, h.log, NewPing(), http.StatusOK)
+	response.Write(
+		w,
+		logger.FromContext(r.Context(), h.log),
+		NewPing(),
+		http.StatusOK,
+	)
 }
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+type RuntimeInfo struct {
+	GoVersion     string        `json:"go_version"`
+	OS            string        `json:"os"`
+	Arch          string        `json:"arch"`
+	NumCPU        int           `json:"num_cpu"`
+	GOMAXPROCS    int           `json:"gomaxprocs"`
+	NumGoroutine  int           `json:"num_goroutine"`
+	UptimeSeconds int64         `json:"uptime_seconds"`
+	Memory        RuntimeMemory `json:"memory"`
+	Build         RuntimeBuild  `json:"build"`
+}
+
+type RuntimeMemory struct {
+	AllocBytes      uint64 `json:"alloc_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	HeapObjects     uint64 `json:"heap_objects"`
+	NumGC           uint32 `json:"num_gc"`
+}
+
+type RuntimeBuild struct {
+	Path     string `json:"path,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Modified bool   `json:"modified"`
+}
+
+type RuntimeHandler struct {
+	log       *slog.Logger
+	startedAt time.Time
+}
+
+func NewRuntimeHandler(log *slog.Logger) *RuntimeHandler {
+	return &RuntimeHandler{
+		log:       log,
+		startedAt: time.Now(),
+	}
+}
+
+func (h *RuntimeHandler) Info(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+
+	runtime.ReadMemStats(&mem)
+
+	response.Write(
+		w, h.log,
+		RuntimeInfo{
+			GoVersion:     runtime.Version(),
+			OS:            runtime.GOOS,
+			Arch:          runtime.GOARCH,
+			NumCPU:        runtime.NumCPU(),
+			GOMAXPROCS:    runtime.GOMAXPROCS(0),
+			NumGoroutine:  runtime.NumGoroutine(),
+			UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+			Memory: RuntimeMemory{
+				AllocBytes:      mem.Alloc,
+				TotalAllocBytes: mem.TotalAlloc,
+				SysBytes:        mem.Sys,
+				HeapObjects:     mem.HeapObjects,
+				NumGC:           mem.NumGC,
+			},
+			Build: readBuildInfo(),
+		},
+		http.StatusOK,
+	)
+}
+
+func readBuildInfo() RuntimeBuild {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return RuntimeBuild{}
+	}
+
+	build := RuntimeBuild{
+		Path:    info.Main.Path,
+		Version: info.Main.Version,
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+
+	return build
+}
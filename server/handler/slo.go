@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/server/response"
+	"github.com/dzherb/mifi-go-microservice/slo"
+)
+
+type SLOTracker interface {
+	Summary() []slo.ObjectiveSummary
+}
+
+type SLOSummaryResponse struct {
+	Objectives []slo.ObjectiveSummary `json:"objectives"`
+}
+
+type SLOHandler struct {
+	log     *slog.Logger
+	tracker SLOTracker
+}
+
+func NewSLOHandler(log *slog.Logger, tracker SLOTracker) *SLOHandler {
+	return &SLOHandler{
+		log:     log,
+		tracker: tracker,
+	}
+}
+
+func (h *SLOHandler) Summary(w http.ResponseWriter, _ *http.Request) {
+	response.Write(
+		w, h.log,
+		SLOSummaryResponse{Objectives: h.tracker.Summary()},
+		http.StatusOK,
+	)
+}
@@ -6,12 +6,14 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"net/mail"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
 	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/openapi"
 	"github.com/dzherb/mifi-go-microservice/server/response"
 	"github.com/dzherb/mifi-go-microservice/service"
 )
@@ -25,30 +27,67 @@ type UserService interface {
 }
 
 type Notifier interface {
-	Send(string, map[string]any)
+	Send(context.Context, string, map[string]any)
 }
 
 type UserHandler struct {
 	log      *slog.Logger
+	metrics  *metric.Metrics
 	service  UserService
 	notifier Notifier
 }
 
 func NewUserHandler(
 	log *slog.Logger,
+	metrics *metric.Metrics,
 	userService UserService,
 	notifier Notifier,
 ) *UserHandler {
 	return &UserHandler{
 		log:      log,
+		metrics:  metrics,
 		service:  userService,
 		notifier: notifier,
 	}
 }
 
+func (h *UserHandler) requestLog(r *http.Request) *slog.Logger {
+	return logger.FromContext(r.Context(), h.log)
+}
+
+// decodeRequestOrWriteError разбирает JSON тело запроса; тело
+// больше лимита BodyLimitMiddleware дает 413, остальные ошибки - 422
+func (h *UserHandler) decodeRequestOrWriteError(
+	w http.ResponseWriter,
+	r *http.Request,
+	dst any,
+) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		response.WriteProblem(
+			w, r, h.requestLog(r),
+			response.NewBodyTooLargeProblem(maxBytesErr.Limit),
+		)
+
+		return false
+	}
+
+	response.WriteProblem(
+		w, r, h.requestLog(r),
+		response.NewProblem(response.ProblemMalformedBody, err.Error()),
+	)
+
+	return false
+}
+
 type UserCreateRequest struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name  string `json:"name" openapi:"minLength=1"`
+	Email string `json:"email" openapi:"format=email"`
 }
 
 type UserResponse model.User
@@ -56,14 +95,7 @@ type UserResponse model.User
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req UserCreateRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		response.Write(
-			w, h.log,
-			response.NewError(err.Error()),
-			http.StatusUnprocessableEntity,
-		)
-
+	if !h.decodeRequestOrWriteError(w, r, &req) {
 		return
 	}
 
@@ -72,23 +104,25 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Email: req.Email,
 	}
 
-	if !h.validateIncomingUserOrWriteError(w, user) {
+	if !h.validateIncomingUserOrWriteError(w, r, user) {
 		return
 	}
 
 	created, err := h.service.Create(r.Context(), user)
 	if err != nil {
-		h.log.Error(
+		h.requestLog(r).ErrorContext(
+			r.Context(),
 			"failed to create user",
 			slog.String("error", err.Error()),
 		)
 
-		response.WriteDefaultError(w, h.log)
+		response.WriteDefaultError(w, r, h.requestLog(r))
 
 		return
 	}
 
-	go h.notifier.Send(
+	h.notifier.Send(
+		context.WithoutCancel(r.Context()),
 		"user_created",
 		map[string]any{
 			"user_id": created.ID,
@@ -97,7 +131,7 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	response.Write(
 		w,
-		h.log,
+		h.requestLog(r),
 		UserResponse(created),
 		http.StatusCreated,
 	)
@@ -110,12 +144,16 @@ type AllUsersResponse struct {
 func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	users, err := h.service.GetAll(r.Context())
 	if err != nil {
-		response.WriteDefaultError(w, h.log)
+		response.WriteDefaultError(w, r, h.requestLog(r))
 
 		return
 	}
 
-	response.Write(w, h.log, AllUsersResponse{Users: users}, http.StatusOK)
+	response.Write(
+		w, h.requestLog(r),
+		AllUsersResponse{Users: users},
+		http.StatusOK,
+	)
 }
 
 func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -127,22 +165,24 @@ func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
 	user, err := h.service.Get(r.Context(), userID)
 	if err != nil {
 		if errors.Is(err, service.ErrUserDoesNotExist) {
-			response.Write(
-				w, h.log,
-				response.NewError(service.ErrUserDoesNotExist.Error()),
-				http.StatusNotFound,
+			response.WriteProblem(
+				w, r, h.requestLog(r),
+				response.NewProblem(
+					response.ProblemNotFound,
+					service.ErrUserDoesNotExist.Error(),
+				),
 			)
 
 			return
 		}
 
-		response.WriteDefaultError(w, h.log)
+		response.WriteDefaultError(w, r, h.requestLog(r))
 
 		return
 	}
 
 	response.Write(
-		w, h.log,
+		w, h.requestLog(r),
 		UserResponse(user),
 		http.StatusOK,
 	)
@@ -162,14 +202,7 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	var req UserUpdateRequest
 
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		response.Write(
-			w, h.log,
-			response.NewError(err.Error()),
-			http.StatusUnprocessableEntity,
-		)
-
+	if !h.decodeRequestOrWriteError(w, r, &req) {
 		return
 	}
 
@@ -179,29 +212,31 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		Email: req.Email,
 	}
 
-	if !h.validateIncomingUserOrWriteError(w, user) {
+	if !h.validateIncomingUserOrWriteError(w, r, user) {
 		return
 	}
 
-	err = h.service.Update(r.Context(), user)
+	err := h.service.Update(r.Context(), user)
 	if err != nil {
 		if errors.Is(err, service.ErrUserDoesNotExist) {
-			response.Write(
-				w, h.log,
-				response.NewError(service.ErrUserDoesNotExist.Error()),
-				http.StatusNotFound,
+			response.WriteProblem(
+				w, r, h.requestLog(r),
+				response.NewProblem(
+					response.ProblemNotFound,
+					service.ErrUserDoesNotExist.Error(),
+				),
 			)
 
 			return
 		}
 
-		response.WriteDefaultError(w, h.log)
+		response.WriteDefaultError(w, r, h.requestLog(r))
 
 		return
 	}
 
 	response.Write(
-		w, h.log,
+		w, h.requestLog(r),
 		UserUpdateResponse(user),
 		http.StatusOK,
 	)
@@ -215,17 +250,24 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	err := h.service.Delete(r.Context(), userID)
 	if err != nil {
-		response.WriteDefaultError(w, h.log)
+		if errors.Is(err, service.ErrUserDoesNotExist) {
+			response.WriteProblem(
+				w, r, h.requestLog(r),
+				response.NewProblem(
+					response.ProblemNotFound,
+					service.ErrUserDoesNotExist.Error(),
+				),
+			)
+
+			return
+		}
+
+		response.WriteDefaultError(w, r, h.requestLog(r))
 
 		return
 	}
 
-	response.Write(w, h.log, nil, http.StatusNoContent)
-}
-
-type UserValidationFailedResponse struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+	response.Write(w, h.requestLog(r), nil, http.StatusNoContent)
 }
 
 func (h *UserHandler) getUserIDParamOrWriteError(
@@ -237,10 +279,13 @@ func (h *UserHandler) getUserIDParamOrWriteError(
 
 	_, err := uuid.Parse(userID)
 	if err != nil {
-		response.Write(
-			w, h.log,
-			response.NewError("invalid user ID"),
-			http.StatusBadRequest,
+		response.WriteProblem(
+			w, r, h.requestLog(r),
+			response.NewValidationProblem([]openapi.Violation{{
+				Parameter: "id",
+				In:        openapi.InPath,
+				Detail:    "must be a valid UUID",
+			}}),
 		)
 
 		return "", false
@@ -249,46 +294,41 @@ func (h *UserHandler) getUserIDParamOrWriteError(
 	return userID, true
 }
 
+// validateIncomingUserOrWriteError проверяет правила model.User;
+// через RootHandler некорректные тела отклоняет проверка по OpenAPI
+// раньше, а здесь остается защита для вызова обработчика без нее
 func (h *UserHandler) validateIncomingUserOrWriteError(
 	w http.ResponseWriter,
+	r *http.Request,
 	user model.User,
 ) (ok bool) {
-	if user.Name == "" {
-		response.Write(
-			w, h.log,
-			UserValidationFailedResponse{
-				Field:   "name",
-				Message: "name is required",
-			},
-			http.StatusBadRequest,
+	var validationErr *model.ValidationError
+	if errors.As(user.Validate(), &validationErr) {
+		h.writeValidationError(
+			w, r,
+			validationErr.Field,
+			validationErr.Message,
 		)
 
-		return
-	}
-
-	if user.Email == "" {
-		response.Write(
-			w, h.log,
-			UserValidationFailedResponse{
-				Field:   "email",
-				Message: "email is required",
-			},
-			http.StatusBadRequest,
-		)
-	}
-
-	if _, err := mail.ParseAddress(user.Email); err != nil {
-		response.Write(
-			w, h.log,
-			UserValidationFailedResponse{
-				Field:   "email",
-				Message: "email not valid: " + err.Error(),
-			},
-			http.StatusBadRequest,
-		)
-
-		return
+		return false
 	}
 
 	return true
 }
+
+func (h *UserHandler) writeValidationError(
+	w http.ResponseWriter,
+	r *http.Request,
+	field string,
+	message string,
+) {
+	h.metrics.UserValidationFailuresTotal.WithLabelValues(field).Inc()
+
+	response.WriteProblem(
+		w, r, h.requestLog(r),
+		response.NewValidationProblem([]openapi.Violation{{
+			Pointer: "/" + field,
+			Detail:  message,
+		}}),
+	)
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+)
+
+type AccessLogConfig struct {
+	// SuccessSampleRate - доля логируемых 2xx ответов, от 0 до 1
+	SuccessSampleRate float64
+	// ExcludePaths - пути, запросы к которым не логируются
+	ExcludePaths []string
+}
+
+func AccessLogMiddleware(
+	log *slog.Logger,
+	cfg AccessLogConfig,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(cfg.ExcludePaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			start := time.Now()
+			rw := &interceptStatusResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.WrittenStatus()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if isSuccessStatus(status) &&
+				rand.Float64() >= cfg.SuccessSampleRate {
+				return
+			}
+
+			logger.FromContext(r.Context(), log).LogAttrs(
+				r.Context(),
+				accessLogLevel(status),
+				"http request",
+				slog.String("method", r.Method),
+				slog.String("route", routeTemplate(r)),
+				slog.Int("status", status),
+				slog.Int64("bytes_written", rw.WrittenBytes()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("client_ip", clientIP(r)),
+				slog.String("user_agent", r.UserAgent()),
+				slog.String("principal", reqctx.Principal(r.Context())),
+			)
+		})
+	}
+}
+
+func isSuccessStatus(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
+
+func accessLogLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
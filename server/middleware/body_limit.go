@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+// BodyLimitMiddleware ограничивает размер тела запроса maxBytes.
+// Запросы с заведомо большим Content-Length отклоняются сразу,
+// остальные читаются через http.MaxBytesReader, и обработчик получает
+// *http.MaxBytesError, когда тело оказывается больше лимита.
+func BodyLimitMiddleware(
+	log *slog.Logger,
+	maxBytes int64,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes <= 0 {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				response.WriteProblem(
+					w, r, logger.FromContext(r.Context(), log),
+					response.NewBodyTooLargeProblem(maxBytes),
+				)
+
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
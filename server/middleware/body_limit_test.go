@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	var readErr error
+
+	h := BodyLimitMiddleware(slog.New(slog.DiscardHandler), 8)(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(r.Body)
+		}),
+	)
+
+	t.Run("content length over limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(
+			http.MethodPost, "/", strings.NewReader("0123456789"),
+		))
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("status = %d, want 413", rec.Code)
+		}
+	})
+
+	t.Run("chunked body over limit", func(t *testing.T) {
+		req := httptest.NewRequest(
+			http.MethodPost, "/", strings.NewReader("0123456789"),
+		)
+		req.ContentLength = -1
+
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		var maxBytesErr *http.MaxBytesError
+		if !errors.As(readErr, &maxBytesErr) {
+			t.Fatalf("read error = %v, want *http.MaxBytesError", readErr)
+		}
+	})
+}
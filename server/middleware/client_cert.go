@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+)
+
+// ClientCertMiddleware сохраняет в контексте запроса идентификатор
+// клиента из проверенного сертификата mTLS. Непроверенные
+// сертификаты, полученные в режиме request, игнорируются.
+func ClientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		principal := CertIdentity(r.TLS.VerifiedChains[0][0])
+		if principal == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		ctx := reqctx.WithPrincipal(r.Context(), principal)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CertIdentity возвращает идентификатор клиента из сертификата:
+// URI из SAN, например SPIFFE ID, затем DNS имя и Common Name
+func CertIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight - максимальное число одновременно обрабатываемых запросов
+	MaxInFlight int
+	// ReservedForReads - слоты, которые не могут занять пишущие запросы
+	ReservedForReads int
+	// QueueTimeout - сколько запрос может ждать свободного слота
+	QueueTimeout time.Duration
+	// RetryAfter - значение заголовка Retry-After для отброшенных запросов
+	RetryAfter time.Duration
+	// ExemptPaths - пути, на которые ограничение не распространяется
+	ExemptPaths []string
+}
+
+// ConcurrencyLimiter ограничивает число одновременно обрабатываемых
+// запросов. Один ограничитель можно разделить между HTTP и gRPC API,
+// чтобы у них был общий запас слотов.
+type ConcurrencyLimiter struct {
+	log        *slog.Logger
+	metrics    *metric.Metrics
+	cfg        ConcurrencyLimitConfig
+	retryAfter string
+
+	// Каждый запрос занимает слот в общем семафоре, пишущие запросы
+	// дополнительно занимают слот в своем, поэтому часть общей емкости
+	// всегда остается доступной только для чтения.
+	all    chan struct{}
+	writes chan struct{}
+}
+
+// NewConcurrencyLimiter возвращает ограничитель; при MaxInFlight <= 0
+// он пропускает все запросы
+func NewConcurrencyLimiter(
+	log *slog.Logger,
+	m *metric.Metrics,
+	cfg ConcurrencyLimitConfig,
+) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		log:     log,
+		metrics: m,
+		cfg:     cfg,
+		retryAfter: strconv.Itoa(
+			max(int(math.Ceil(cfg.RetryAfter.Seconds())), 1),
+		),
+	}
+
+	if cfg.MaxInFlight > 0 {
+		l.all = make(chan struct{}, cfg.MaxInFlight)
+		l.writes = make(
+			chan struct{},
+			max(cfg.MaxInFlight-cfg.ReservedForReads, 1),
+		)
+	}
+
+	return l
+}
+
+func ConcurrencyLimitMiddleware(
+	log *slog.Logger,
+	m *metric.Metrics,
+	cfg ConcurrencyLimitConfig,
+) func(http.Handler) http.Handler {
+	return NewConcurrencyLimiter(log, m, cfg).Middleware
+}
+
+// Acquire ждет свободного слота не дольше QueueTimeout; если слот
+// не освободился, возвращает nil и причину отказа
+func (l *ConcurrencyLimiter) Acquire(
+	ctx context.Context,
+	read bool,
+) (release func(), reason string) {
+	if l.all == nil {
+		return func() {}, ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.cfg.QueueTimeout)
+	defer cancel()
+
+	return acquireSlots(ctx, read, l.all, l.writes, l.metrics.QueuedRequests)
+}
+
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	if l.all == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context(), l.log)
+
+		if slices.Contains(l.cfg.ExemptPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		release, reason := l.Acquire(r.Context(), isReadRequest(r))
+		if release == nil {
+			l.metrics.ShedRequestsTotal.
+				WithLabelValues(methodLabel(r), reason).
+				Inc()
+
+			w.Header().Set("Retry-After", l.retryAfter)
+			response.WriteProblem(
+				w, r, log,
+				response.NewProblem(
+					response.ProblemOverloaded,
+					"server is overloaded, try again later",
+				),
+			)
+
+			return
+		}
+
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+const (
+	shedReasonQueueTimeout = "queue_timeout"
+	shedReasonCanceled     = "canceled"
+)
+
+func acquireSlots(
+	ctx context.Context,
+	read bool,
+	all chan struct{},
+	writes chan struct{},
+	queued prometheus.Gauge,
+) (release func(), reason string) {
+	if !read {
+		if !acquire(ctx, writes, queued) {
+			return nil, shedReason(ctx)
+		}
+	}
+
+	if !acquire(ctx, all, queued) {
+		if !read {
+			<-writes
+		}
+
+		return nil, shedReason(ctx)
+	}
+
+	return func() {
+		<-all
+
+		if !read {
+			<-writes
+		}
+	}, ""
+}
+
+// acquire занимает слот; queued учитывает запрос, только пока
+// он действительно ждет свободного слота
+func acquire(
+	ctx context.Context,
+	sem chan struct{},
+	queued prometheus.Gauge,
+) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	queued.Inc()
+	defer queued.Dec()
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func shedReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return shedReasonCanceled
+	}
+
+	return shedReasonQueueTimeout
+}
+
+func isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
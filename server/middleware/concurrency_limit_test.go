@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+func TestConcurrencyLimitShedsQueuedRequest(t *testing.T) {
+	m := metric.New(prometheus.NewRegistry())
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	h := ConcurrencyLimitMiddleware(
+		slog.New(slog.DiscardHandler),
+		m,
+		ConcurrencyLimitConfig{
+			MaxInFlight:  1,
+			QueueTimeout: 200 * time.Millisecond,
+			RetryAfter:   time.Second,
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	first := make(chan int)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		first <- rec.Code
+	}()
+
+	<-started
+
+	// запрос, сразу получивший слот, не считается ожидающим
+	if got := testutil.ToFloat64(m.QueuedRequests); got != 0 {
+		t.Fatalf("QueuedRequests with one in-flight request = %v, want 0", got)
+	}
+
+	second := make(chan *httptest.ResponseRecorder)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		second <- rec
+	}()
+
+	waitFor(t, func() bool {
+		return testutil.ToFloat64(m.QueuedRequests) == 1
+	})
+
+	rec := <-second
+
+	close(unblock)
+
+	if code := <-first; code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", code)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("shed request status = %d, want 503", rec.Code)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != response.ContentTypeProblem {
+		t.Fatalf("shed request content type = %q", got)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("Retry-After = %q, want 1", got)
+	}
+
+	shed := testutil.ToFloat64(
+		m.ShedRequestsTotal.WithLabelValues(
+			http.MethodGet,
+			shedReasonQueueTimeout,
+		),
+	)
+	if shed != 1 {
+		t.Fatalf("ShedRequestsTotal = %v, want 1", shed)
+	}
+
+	if got := testutil.ToFloat64(m.QueuedRequests); got != 0 {
+		t.Fatalf("QueuedRequests after shedding = %v, want 0", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+	"github.com/dzherb/mifi-go-microservice/service"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+type IdempotencyStore interface {
+	Get(context.Context, string) (model.IdempotencyRecord, error)
+	Save(context.Context, model.IdempotencyRecord) error
+}
+
+func IdempotencyMiddleware(
+	log *slog.Logger,
+	store IdempotencyStore,
+) func(http.Handler) http.Handler {
+	var inProgress sync.Map
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := logger.FromContext(r.Context(), log)
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if len(key) > MaxIdempotencyKeyLength {
+				response.WriteProblem(
+					w, r, log,
+					response.NewValidationProblem([]openapi.Violation{{
+						Parameter: IdempotencyKeyHeader,
+						In:        openapi.InHeader,
+						Detail: fmt.Sprintf(
+							"must be at most %d characters long",
+							MaxIdempotencyKeyLength,
+						),
+					}}),
+				)
+
+				return
+			}
+
+			body, err := io.ReadAll(
+				io.LimitReader(r.Body, maxIdempotentRequestBytes+1),
+			)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				response.WriteProblem(
+					w, r, log,
+					response.NewBodyTooLargeProblem(maxBytesErr.Limit),
+				)
+
+				return
+			}
+
+			if err != nil {
+				response.WriteProblem(
+					w, r, log,
+					response.NewProblem(
+						response.ProblemBadRequest,
+						"failed to read request body",
+					),
+				)
+
+				return
+			}
+
+			if len(body) > maxIdempotentRequestBytes {
+				response.WriteProblem(
+					w, r, log,
+					response.NewBodyTooLargeProblem(maxIdempotentRequestBytes),
+				)
+
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// ключ выбирает клиент, поэтому одинаковые ключи разных
+			// клиентов не должны получать ответы друг друга
+			key = scopedIdempotencyKey(reqctx.Principal(r.Context()), key)
+
+			// Повторы обычно приходят, пока первый запрос еще
+			// обрабатывается, поэтому одновременно с одним ключом
+			// работает только один запрос; блокировка действует
+			// в пределах процесса, между репликами ее нет
+			if _, loaded := inProgress.LoadOrStore(key, struct{}{}); loaded {
+				response.WriteProblem(
+					w, r, log,
+					response.NewProblem(
+						response.ProblemIdempotencyInProgress,
+						"retry after the first request completes",
+					),
+				)
+
+				return
+			}
+
+			defer inProgress.Delete(key)
+
+			fingerprint := requestFingerprint(r, body)
+
+			record, err := store.Get(r.Context(), key)
+
+			switch {
+			case err == nil:
+				if record.Fingerprint != fingerprint {
+					response.WriteProblem(
+						w, r, log,
+						response.NewProblem(
+							response.ProblemIdempotencyKeyReused,
+							"use a new idempotency key for a different request",
+						),
+					)
+
+					return
+				}
+
+				replayRecord(w, log, record)
+
+				return
+			case !errors.Is(err, service.ErrIdempotencyRecordNotFound):
+				log.Error(
+					"failed to get idempotency record",
+					slog.String("error", err.Error()),
+				)
+
+				response.WriteDefaultError(w, r, log)
+
+				return
+			}
+
+			rw := &recordingResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(rw, r)
+
+			// ошибки сервера не сохраняются, чтобы клиент мог повторить
+			// запрос с тем же ключом
+			if rw.statusCode >= http.StatusInternalServerError {
+				return
+			}
+
+			err = store.Save(
+				context.WithoutCancel(r.Context()),
+				model.IdempotencyRecord{
+					Key:         key,
+					Fingerprint: fingerprint,
+					StatusCode:  rw.statusCode,
+					ContentType: rw.Header().Get("Content-Type"),
+					Body:        rw.body.Bytes(),
+				},
+			)
+			if err != nil {
+				log.Error(
+					"failed to save idempotency record",
+					slog.String("error", err.Error()),
+				)
+			}
+		})
+	}
+}
+
+// scopedIdempotencyKey добавляет к ключу идентификатор клиента
+// из mTLS; без клиентских сертификатов все клиенты делят одно
+// пространство ключей
+func scopedIdempotencyKey(principal, key string) string {
+	return principal + "\x00" + key
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayRecord(
+	w http.ResponseWriter,
+	log *slog.Logger,
+	record model.IdempotencyRecord,
+) {
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+
+	_, err := w.Write(record.Body)
+	if err != nil {
+		log.Error(
+			"error writing replayed response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	if rw.statusCode == 0 {
+		rw.statusCode = code
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+
+	rw.body.Write(b)
+
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/service"
+)
+
+type memIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+func (s *memIdempotencyStore) Get(
+	_ context.Context,
+	key string,
+) (model.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return record, service.ErrIdempotencyRecordNotFound
+	}
+
+	return record, nil
+}
+
+func (s *memIdempotencyStore) Save(
+	_ context.Context,
+	record model.IdempotencyRecord,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records == nil {
+		s.records = make(map[string]model.IdempotencyRecord)
+	}
+
+	s.records[record.Key] = record
+
+	return nil
+}
+
+func newIdempotencyRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(
+		http.MethodPost, "/api/users", strings.NewReader(body),
+	)
+	r.Header.Set(IdempotencyKeyHeader, key)
+
+	return r
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	calls := 0
+
+	h := IdempotencyMiddleware(
+		slog.New(slog.DiscardHandler),
+		&memIdempotencyStore{},
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, newIdempotencyRequest("k", `{"name":"a"}`))
+
+	second := httptest.NewRecorder()
+	h.ServeHTTP(second, newIdempotencyRequest("k", `{"name":"a"}`))
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+
+	if second.Code != http.StatusCreated {
+		t.Fatalf("replayed status = %d, want 201", second.Code)
+	}
+
+	if got := second.Header().Get(IdempotentReplayedHeader); got != "true" {
+		t.Fatalf("%s = %q, want true", IdempotentReplayedHeader, got)
+	}
+
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("first response must not be marked as replayed")
+	}
+
+	if got := second.Body.String(); got != `{"id":"1"}` {
+		t.Fatalf("replayed body = %s", got)
+	}
+
+	if got := second.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("replayed content type = %q", got)
+	}
+}
+
+func TestIdempotencyRejectsKeyReuseWithDifferentRequest(t *testing.T) {
+	h := IdempotencyMiddleware(
+		slog.New(slog.DiscardHandler),
+		&memIdempotencyStore{},
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	h.ServeHTTP(
+		httptest.NewRecorder(),
+		newIdempotencyRequest("k", `{"name":"a"}`),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newIdempotencyRequest("k", `{"name":"b"}`))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestIdempotencyRejectsConcurrentRequestWithSameKey(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	h := IdempotencyMiddleware(
+		slog.New(slog.DiscardHandler),
+		&memIdempotencyStore{},
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-unblock
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan int)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newIdempotencyRequest("k", `{}`))
+		done <- rec.Code
+	}()
+
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newIdempotencyRequest("k", `{}`))
+
+	close(unblock)
+
+	if code := <-done; code != http.StatusCreated {
+		t.Fatalf("first request status = %d, want 201", code)
+	}
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("concurrent request status = %d, want 409", rec.Code)
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	statuses := []int{
+		http.StatusInternalServerError,
+		http.StatusCreated,
+	}
+	calls := 0
+
+	h := IdempotencyMiddleware(
+		slog.New(slog.DiscardHandler),
+		&memIdempotencyStore{},
+	)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(statuses[calls])
+		calls++
+	}))
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, newIdempotencyRequest("k", `{}`))
+
+	second := httptest.NewRecorder()
+	h.ServeHTTP(second, newIdempotencyRequest("k", `{}`))
+
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+
+	if second.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want 201", second.Code)
+	}
+
+	if second.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("retry after a server error must not be replayed")
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// InFlightTracker считает запросы, которые еще обрабатываются,
+// чтобы при остановке дождаться их до закрытия хранилища
+type InFlightTracker struct {
+	wg    sync.WaitGroup
+	count atomic.Int64
+}
+
+func NewInFlightTracker() *InFlightTracker {
+	return &InFlightTracker{}
+}
+
+func (t *InFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		t.count.Add(1)
+
+		defer func() {
+			t.count.Add(-1)
+			t.wg.Done()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *InFlightTracker) Count() int64 {
+	return t.count.Load()
+}
+
+// Wait ждет завершения всех начатых запросов, но не дольше,
+// чем живет ctx
+func (t *InFlightTracker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf(
+			"%d requests still in flight: %w",
+			t.Count(),
+			ctx.Err(),
+		)
+	}
+}
@@ -9,38 +9,51 @@ import (
 	"github.com/dzherb/mifi-go-microservice/metric"
 )
 
-func CollectRequestsMetrics(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		metric.ActiveRequests.Inc()
-		start := time.Now()
+func CollectRequestsMetrics(
+	m *metric.Metrics,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.ActiveRequests.Inc()
+			start := time.Now()
 
-		rw := &interceptStatusResponseWriter{ResponseWriter: w}
+			rw := &interceptStatusResponseWriter{ResponseWriter: w}
 
-		defer func() {
-			metric.ActiveRequests.Dec()
+			defer func() {
+				m.ActiveRequests.Dec()
 
-			duration := time.Since(start).Seconds()
-			statusCode := strconv.Itoa(rw.WrittenStatus())
+				duration := time.Since(start).Seconds()
+				statusCode := strconv.Itoa(rw.WrittenStatus())
 
-			metric.TotalRequests.WithLabelValues(r.Method, r.URL.Path, statusCode).
-				Inc()
-			metric.RequestDuration.WithLabelValues(r.Method, r.URL.Path).
-				Observe(duration)
+				// шаблон маршрута вместо пути, чтобы идентификаторы
+				// не порождали новые временные ряды
+				endpoint := routeTemplate(r)
+				method := methodLabel(r)
 
-			if rw.WrittenStatus() >= http.StatusBadRequest ||
-				rw.WrittenStatus() == 0 {
-				metric.ErrorsTotal.WithLabelValues(r.Method, r.URL.Path, statusCode).
+				m.TotalRequests.
+					WithLabelValues(method, endpoint, statusCode).
 					Inc()
-			}
-		}()
+				m.RequestDuration.
+					WithLabelValues(method, endpoint).
+					Observe(duration)
 
-		next.ServeHTTP(rw, r)
-	})
+				if rw.WrittenStatus() >= http.StatusBadRequest ||
+					rw.WrittenStatus() == 0 {
+					m.ErrorsTotal.
+						WithLabelValues(method, endpoint, statusCode).
+						Inc()
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
 }
 
 type interceptStatusResponseWriter struct {
 	http.ResponseWriter
-	statusCode atomic.Int64
+	statusCode   atomic.Int64
+	bytesWritten atomic.Int64
 }
 
 func (rw *interceptStatusResponseWriter) WriteHeader(code int) {
@@ -51,9 +64,16 @@ func (rw *interceptStatusResponseWriter) WriteHeader(code int) {
 func (rw *interceptStatusResponseWriter) Write(b []byte) (int, error) {
 	rw.statusCode.CompareAndSwap(0, http.StatusOK)
 
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten.Add(int64(n))
+
+	return n, err
 }
 
 func (rw *interceptStatusResponseWriter) WrittenStatus() int {
 	return int(rw.statusCode.Load())
 }
+
+func (rw *interceptStatusResponseWriter) WrittenBytes() int64 {
+	return rw.bytesWritten.Load()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+)
+
+func TestCollectRequestsMetricsBoundedCardinality(t *testing.T) {
+	m := metric.New(prometheus.NewRegistry())
+	collect := CollectRequestsMetrics(m)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := mux.NewRouter()
+	r.Use(collect)
+	r.Handle("/users/{id}", ok).Methods(http.MethodGet)
+	r.NotFoundHandler = collect(http.NotFoundHandler())
+	r.MethodNotAllowedHandler = collect(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}),
+	)
+
+	methods := []string{
+		http.MethodGet, http.MethodPost, http.MethodDelete,
+	}
+
+	for i := range 500 {
+		method := methods[rand.IntN(len(methods))]
+		if i%2 == 0 {
+			method = fmt.Sprintf("M%d", rand.Int())
+		}
+
+		path := fmt.Sprintf("/users/%d", rand.Int())
+		if i%3 == 0 {
+			path = fmt.Sprintf("/random/%d/%d", rand.Int(), i)
+		}
+
+		r.ServeHTTP(
+			httptest.NewRecorder(),
+			httptest.NewRequest(method, path, nil),
+		)
+	}
+
+	// 4 метки метода (3 известных и OTHER), 2 маршрута
+	// (шаблон и unmatched) и 3 статуса (200, 404, 405)
+	const maxSeries = 4 * 2 * 3
+
+	got := testutil.CollectAndCount(m.TotalRequests)
+	if got == 0 || got > maxSeries {
+		t.Fatalf("http_requests_total has %d series, want 1..%d", got, maxSeries)
+	}
+
+	got = testutil.CollectAndCount(m.RequestDuration)
+	if got == 0 || got > 4*2 {
+		t.Fatalf("http_request_duration has %d series, want 1..%d", got, 4*2)
+	}
+}
+
+func TestMethodLabel(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, http.MethodGet},
+		{http.MethodPatch, http.MethodPatch},
+		{"FOO", otherMethod},
+		{"get", otherMethod},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/", nil)
+
+		if got := methodLabel(r); got != tt.want {
+			t.Errorf("methodLabel(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
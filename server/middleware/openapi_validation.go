@@ -0,0 +1,372 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+const (
+	// maxValidatedRequestBytes ограничивает чтение тела для проверки,
+	// даже если BodyLimitMiddleware выключен, как в IdempotencyMiddleware
+	maxValidatedRequestBytes = 1 << 20
+	// maxValidatedResponseBytes - ответы больше этого размера
+	// не проверяются, чтобы не держать их целиком в памяти
+	maxValidatedResponseBytes = 1 << 20
+)
+
+type OpenAPIValidationConfig struct {
+	// ValidateResponses включает проверку ответов по документу;
+	// расхождения только пишутся в лог, ответ клиенту не меняется
+	ValidateResponses bool
+	// OnInvalidRequest, если задан, получает нарушения отклоненного
+	// запроса, например чтобы посчитать бизнес-метрики по полям
+	OnInvalidRequest func(*openapi.Operation, []openapi.Violation)
+}
+
+// OpenAPIValidationMiddleware проверяет параметры пути, запроса,
+// заголовки и JSON тело по операции документа, которая соответствует
+// маршруту, и отвечает 400 со всеми нарушениями сразу. Маршруты
+// без операции в документе пропускаются.
+func OpenAPIValidationMiddleware(
+	log *slog.Logger,
+	m *metric.Metrics,
+	doc *openapi.Document,
+	cfg OpenAPIValidationConfig,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := openapi.PathFromTemplate(routeTemplate(r))
+
+			op := doc.Operation(r.Method, path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			log := logger.FromContext(r.Context(), log)
+
+			violations := validateParameters(doc, op, r)
+
+			body, ok := validateBodyOrWriteError(w, r, log, doc, op)
+			if !ok {
+				return
+			}
+
+			violations = append(violations, body...)
+
+			if len(violations) > 0 {
+				for _, v := range violations {
+					in := v.In
+					if in == "" {
+						in = "body"
+					}
+
+					m.RequestValidationFailuresTotal.
+						WithLabelValues(r.Method, path, in).
+						Inc()
+				}
+
+				if cfg.OnInvalidRequest != nil {
+					cfg.OnInvalidRequest(op, violations)
+				}
+
+				response.WriteProblem(
+					w, r, log,
+					response.NewValidationProblem(violations),
+				)
+
+				return
+			}
+
+			if !cfg.ValidateResponses {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			rw := &recordingBodyResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(rw, r)
+
+			validateResponse(log, m, doc, op, r, path, rw)
+		})
+	}
+}
+
+func validateParameters(
+	doc *openapi.Document,
+	op *openapi.Operation,
+	r *http.Request,
+) []openapi.Violation {
+	var (
+		violations []openapi.Violation
+		vars       = mux.Vars(r)
+		query      = r.URL.Query()
+	)
+
+	for _, p := range op.Parameters {
+		var (
+			value   string
+			present bool
+		)
+
+		switch p.In {
+		case openapi.InPath:
+			value, present = vars[p.Name]
+		case openapi.InQuery:
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case openapi.InHeader:
+			values := r.Header.Values(p.Name)
+			present = len(values) > 0
+			if present {
+				value = values[0]
+			}
+		}
+
+		violations = append(
+			violations,
+			doc.ValidateParameter(p, value, present)...,
+		)
+	}
+
+	return violations
+}
+
+// validateBodyOrWriteError читает и проверяет JSON тело, а затем
+// возвращает его в r.Body для обработчика. Ошибки чтения и разбора
+// JSON отвечают так же, как обработчики: 413 и 422.
+func validateBodyOrWriteError(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	doc *openapi.Document,
+	op *openapi.Operation,
+) ([]openapi.Violation, bool) {
+	if op.RequestBody == nil {
+		return nil, true
+	}
+
+	media, ok := op.RequestBody.Content[openapi.ContentTypeJSON]
+	if !ok {
+		return nil, true
+	}
+
+	data, err := io.ReadAll(
+		io.LimitReader(r.Body, maxValidatedRequestBytes+1),
+	)
+
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		response.WriteProblem(
+			w, r, log,
+			response.NewBodyTooLargeProblem(maxBytesErr.Limit),
+		)
+
+		return nil, false
+	case err != nil:
+		response.WriteProblem(
+			w, r, log,
+			response.NewProblem(
+				response.ProblemBadRequest,
+				"failed to read request body",
+			),
+		)
+
+		return nil, false
+	case len(data) > maxValidatedRequestBytes:
+		response.WriteProblem(
+			w, r, log,
+			response.NewBodyTooLargeProblem(maxValidatedRequestBytes),
+		)
+
+		return nil, false
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.RequestBody.Required {
+			return []openapi.Violation{{
+				Detail: "request body is required",
+			}}, true
+		}
+
+		return nil, true
+	}
+
+	value, err := decodeJSON(data)
+	if err != nil {
+		response.WriteProblem(
+			w, r, log,
+			response.NewProblem(
+				response.ProblemMalformedBody,
+				"invalid JSON: "+err.Error(),
+			),
+		)
+
+		return nil, false
+	}
+
+	return doc.ValidateJSON(media.Schema, value), true
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+
+	err := dec.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+
+	return value, nil
+}
+
+// validateResponse сверяет записанный ответ с документом и пишет
+// расхождения в лог, чтобы заметить, что код и документ разошлись
+func validateResponse(
+	log *slog.Logger,
+	m *metric.Metrics,
+	doc *openapi.Document,
+	op *openapi.Operation,
+	r *http.Request,
+	path string,
+	rw *recordingBodyResponseWriter,
+) {
+	status := rw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	drift := func(msg string, attrs ...slog.Attr) {
+		m.ResponseValidationFailuresTotal.
+			WithLabelValues(r.Method, path, strconv.Itoa(status)).
+			Inc()
+
+		attrs = append(attrs, slog.Int("status", status))
+
+		log.LogAttrs(r.Context(), slog.LevelWarn, msg, attrs...)
+	}
+
+	spec, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		spec, ok = op.Responses["default"]
+	}
+
+	if !ok {
+		drift("response status is not documented")
+
+		return
+	}
+
+	if rw.overflow {
+		return
+	}
+
+	if len(spec.Content) == 0 {
+		if rw.body.Len() > 0 {
+			drift("response has undocumented body")
+		}
+
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+
+	media, ok := spec.Content[contentType]
+	if !ok {
+		drift(
+			"response content type is not documented",
+			slog.String("content_type", contentType),
+		)
+
+		return
+	}
+
+	if !isJSONContentType(contentType) {
+		return
+	}
+
+	value, err := decodeJSON(rw.body.Bytes())
+	if err != nil {
+		drift(
+			"response body is not valid JSON",
+			slog.String("error", err.Error()),
+		)
+
+		return
+	}
+
+	violations := doc.ValidateJSON(media.Schema, value)
+	if len(violations) > 0 {
+		drift(
+			"response does not match OpenAPI document",
+			slog.Any("violations", violations),
+		)
+	}
+}
+
+// isJSONContentType учитывает и application/json, и типы
+// с суффиксом +json, например application/problem+json
+func isJSONContentType(contentType string) bool {
+	return contentType == openapi.ContentTypeJSON ||
+		strings.HasSuffix(contentType, "+json")
+}
+
+// recordingBodyResponseWriter копирует тело ответа для проверки,
+// пока оно не больше maxValidatedResponseBytes
+type recordingBodyResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rw *recordingBodyResponseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingBodyResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	if !rw.overflow {
+		if rw.body.Len()+len(b) > maxValidatedResponseBytes {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(b)
+		}
+	}
+
+	return rw.ResponseWriter.Write(b)
+}
@@ -1,26 +1,59 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
 
 	"golang.org/x/time/rate"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/response"
 )
 
-func RateLimitMiddleware(
+// RateLimiter ограничивает частоту запросов общим token bucket;
+// лимиты можно менять, не пересоздавая обработчики
+type RateLimiter struct {
+	log     *slog.Logger
+	limiter *rate.Limiter
+}
+
+func NewRateLimiter(
+	log *slog.Logger,
 	reqPerSec float64,
 	burst int,
-) func(http.Handler) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(reqPerSec), burst)
+) *RateLimiter {
+	return &RateLimiter{
+		log:     log,
+		limiter: rate.NewLimiter(rate.Limit(reqPerSec), burst),
+	}
+}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
-				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+// SetLimit применяет новые лимиты к следующим запросам
+func (l *RateLimiter) SetLimit(reqPerSec float64, burst int) {
+	l.limiter.SetLimit(rate.Limit(reqPerSec))
+	l.limiter.SetBurst(burst)
+}
 
-				return
-			}
+// Allow забирает токен для одного запроса; false означает,
+// что лимит исчерпан
+func (l *RateLimiter) Allow() bool {
+	return l.limiter.Allow()
+}
 
-			next.ServeHTTP(w, r)
-		})
-	}
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow() {
+			response.WriteProblem(
+				w, r, logger.FromContext(r.Context(), l.log),
+				response.NewProblem(
+					response.ProblemRateLimited,
+					"request rate limit exceeded",
+				),
+			)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+func RequestIDMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !IsValidRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+
+			w.Header().Set(RequestIDHeader, requestID)
+
+			requestLog := log.With(
+				slog.String("request_id", requestID),
+				slog.String("method", r.Method),
+				slog.String("route", routeTemplate(r)),
+			)
+
+			ctx := reqctx.WithRequestID(r.Context(), requestID)
+			ctx = logger.WithContext(ctx, requestLog)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IsValidRequestID отсекает пустые, слишком длинные и содержащие
+// непечатаемые символы идентификаторы, чтобы они не попадали в логи
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const unmatchedRoute = "unmatched"
+
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+
+	return tpl
+}
+
+// otherMethod заменяет в метриках нестандартные методы, чтобы
+// клиент не мог создавать новые временные ряды
+const otherMethod = "OTHER"
+
+func methodLabel(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodConnect,
+		http.MethodOptions, http.MethodTrace:
+		return r.Method
+	default:
+		return otherMethod
+	}
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/handler"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+)
+
+var apiInfo = openapi.Info{
+	Title:   serviceName,
+	Version: "1.0.0",
+	Description: "Управление пользователями. Ошибки возвращаются " +
+		"как application/problem+json (RFC 9457): вид ошибки задает " +
+		"type, instance содержит идентификатор запроса, нарушения " +
+		"схемы перечислены в errors с JSON Pointer на поле или " +
+		"именем параметра.",
+}
+
+// apiOperations описывает каждый маршрут RootHandler; маршрут без
+// описания не даст собрать документ и запустить сервер
+func apiOperations() openapi.Operations {
+	userID := openapi.PathParam(
+		"id",
+		"идентификатор пользователя",
+		&openapi.Schema{Type: "string", Format: "uuid"},
+	)
+
+	return openapi.Operations{
+		"GET /api/ping": {
+			OperationID: "ping",
+			Summary:     "Проверка доступности API",
+			Tags:        []string{"service"},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.JSONResponse[handler.Ping](
+					"время сервера",
+				),
+			}),
+		},
+		"GET /api/openapi.json": {
+			OperationID: "getOpenAPIDocument",
+			Summary:     "Этот документ",
+			Tags:        []string{"service"},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.SchemaResponse(
+					"документ OpenAPI 3.1",
+					openapi.ContentTypeJSON,
+					&openapi.Schema{Type: "object"},
+				),
+			}),
+		},
+		"GET /api/docs": {
+			OperationID: "getDocs",
+			Summary:     "Документация API в Swagger UI",
+			Tags:        []string{"service"},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.SchemaResponse(
+					"HTML страница",
+					"text/html",
+					&openapi.Schema{Type: "string"},
+				),
+			}),
+		},
+		"GET /api/users": {
+			OperationID: "listUsers",
+			Summary:     "Список пользователей",
+			Tags:        []string{"users"},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.JSONResponse[handler.AllUsersResponse](
+					"все пользователи",
+				),
+			}),
+		},
+		"POST /api/users": {
+			OperationID: "createUser",
+			Summary:     "Создание пользователя",
+			Tags:        []string{"users"},
+			Parameters: []*openapi.Parameter{
+				openapi.HeaderParam(
+					middleware.IdempotencyKeyHeader,
+					"повтор запроса с тем же ключом вернет "+
+						"сохраненный ответ вместо создания дубля",
+					&openapi.Schema{
+						Type:      "string",
+						MaxLength: ptr(middleware.MaxIdempotencyKeyLength),
+					},
+				),
+			},
+			RequestBody: openapi.JSONBody[handler.UserCreateRequest](),
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusCreated: openapi.JSONResponse[handler.UserResponse](
+					"пользователь создан",
+				),
+				http.StatusBadRequest: errorResponse(
+					"тело или ключ идемпотентности не соответствуют схеме",
+				),
+				http.StatusConflict: errorResponse(
+					"запрос с этим ключом идемпотентности еще выполняется",
+				),
+				http.StatusRequestEntityTooLarge: errorResponse(
+					"тело запроса больше лимита",
+				),
+				http.StatusUnprocessableEntity: errorResponse(
+					"тело не разбирается как JSON или ключ " +
+						"идемпотентности использован с другим запросом",
+				),
+			}),
+		},
+		"GET /api/users/{id}": {
+			OperationID: "getUser",
+			Summary:     "Пользователь по идентификатору",
+			Tags:        []string{"users"},
+			Parameters:  []*openapi.Parameter{userID},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.JSONResponse[handler.UserResponse](
+					"пользователь",
+				),
+				http.StatusBadRequest: errorResponse(
+					"некорректный идентификатор",
+				),
+				http.StatusNotFound: errorResponse("пользователь не найден"),
+			}),
+		},
+		"PUT /api/users/{id}": {
+			OperationID: "updateUser",
+			Summary:     "Изменение пользователя",
+			Tags:        []string{"users"},
+			Parameters:  []*openapi.Parameter{userID},
+			RequestBody: openapi.JSONBody[handler.UserUpdateRequest](),
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusOK: openapi.JSONResponse[handler.UserUpdateResponse](
+					"пользователь изменен",
+				),
+				http.StatusBadRequest: errorResponse(
+					"идентификатор или тело не соответствуют схеме",
+				),
+				http.StatusNotFound: errorResponse("пользователь не найден"),
+				http.StatusRequestEntityTooLarge: errorResponse(
+					"тело запроса больше лимита",
+				),
+				http.StatusUnprocessableEntity: errorResponse(
+					"тело не разбирается как JSON",
+				),
+			}),
+		},
+		"DELETE /api/users/{id}": {
+			OperationID: "deleteUser",
+			Summary:     "Удаление пользователя",
+			Tags:        []string{"users"},
+			Parameters:  []*openapi.Parameter{userID},
+			Responses: apiResponses(map[int]*openapi.Response{
+				http.StatusNoContent: openapi.EmptyResponse(
+					"пользователь удален",
+				),
+				http.StatusBadRequest: errorResponse(
+					"некорректный идентификатор",
+				),
+				http.StatusNotFound: errorResponse("пользователь не найден"),
+			}),
+		},
+	}
+}
+
+// apiResponses добавляет к ответам операции те, что может вернуть
+// любой маршрут /api из-за общих middleware
+func apiResponses(
+	responses map[int]*openapi.Response,
+) map[string]*openapi.Response {
+	all := map[int]*openapi.Response{
+		http.StatusTooManyRequests:     errorResponse("превышен лимит запросов"),
+		http.StatusInternalServerError: errorResponse("внутренняя ошибка"),
+		http.StatusServiceUnavailable: errorResponse(
+			"сервер перегружен, повторите после Retry-After",
+		),
+	}
+
+	maps.Copy(all, responses)
+
+	out := make(map[string]*openapi.Response, len(all))
+	for status, resp := range all {
+		out[strconv.Itoa(status)] = resp
+	}
+
+	return out
+}
+
+// countUserValidationFailures считает нарушения в телах запросов
+// пользователей в user_validation_failures_total: такие запросы
+// отклоняются до обработчика, который раньше считал эту метрику
+func countUserValidationFailures(
+	m *metric.Metrics,
+) func(*openapi.Operation, []openapi.Violation) {
+	return func(op *openapi.Operation, violations []openapi.Violation) {
+		if op.RequestBody == nil || !slices.Contains(op.Tags, "users") {
+			return
+		}
+
+		for _, v := range violations {
+			// поле верхнего уровня, как в model.ValidationError
+			field, _, _ := strings.Cut(strings.TrimPrefix(v.Pointer, "/"), "/")
+			if field == "" {
+				continue
+			}
+
+			m.UserValidationFailuresTotal.WithLabelValues(field).Inc()
+		}
+	}
+}
+
+// errorResponse описывает ошибку в формате RFC 9457
+func errorResponse(description string) *openapi.Response {
+	return openapi.ContentResponse[response.Problem](
+		description,
+		response.ContentTypeProblem,
+	)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
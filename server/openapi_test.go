@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+	"github.com/dzherb/mifi-go-microservice/server/response"
+	"github.com/dzherb/mifi-go-microservice/service"
+	"github.com/dzherb/mifi-go-microservice/storage"
+)
+
+// nopStorage нужен только для сборки обработчиков, запросы
+// в этих тестах до хранилища не доходят
+type nopStorage[T any] struct{}
+
+func (nopStorage[T]) Set(context.Context, string, T) error { return nil }
+
+func (nopStorage[T]) Get(context.Context, string) (T, error) {
+	var zero T
+
+	return zero, storage.ErrKeyNotFound
+}
+
+func (nopStorage[T]) GetAll(context.Context) ([]T, error) { return nil, nil }
+
+func (nopStorage[T]) Count(context.Context) (int, error) { return 0, nil }
+
+func (nopStorage[T]) Delete(context.Context, string) error { return nil }
+
+func newTestRootHandler(t *testing.T) (*mux.Router, *metric.Metrics) {
+	t.Helper()
+
+	router, m, _ := newRootHandlerWith(
+		t,
+		nopStorage[model.User]{},
+		&APIConfig{MaxBodyBytes: 1 << 20},
+		middleware.ConcurrencyLimitConfig{},
+	)
+
+	return router, m
+}
+
+func newRootHandlerWith(
+	t *testing.T,
+	users service.Storage[model.User],
+	cfg *APIConfig,
+	limits middleware.ConcurrencyLimitConfig,
+) (*mux.Router, *metric.Metrics, *prometheus.Registry) {
+	t.Helper()
+
+	log := slog.New(slog.DiscardHandler)
+	reg := prometheus.NewRegistry()
+	m := metric.New(reg)
+
+	h, err := RootHandler(
+		log,
+		m,
+		service.NewUserService(log, m, users),
+		service.NewNotifier(log, m, service.NotifierConfig{QueueSize: 1}),
+		service.NewIdempotencyService(
+			log,
+			nopStorage[model.IdempotencyRecord]{},
+			time.Hour,
+		),
+		middleware.NewRateLimiter(log, 1000, 1000),
+		middleware.NewConcurrencyLimiter(log, m, limits),
+		cfg,
+	)
+	if err != nil {
+		t.Fatalf("RootHandler() error = %v", err)
+	}
+
+	router, ok := h.(*mux.Router)
+	if !ok {
+		t.Fatalf("RootHandler() returned %T, want *mux.Router", h)
+	}
+
+	return router, m, reg
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	router, _ := newTestRootHandler(t)
+	ops := apiOperations()
+
+	routes := 0
+
+	err := router.Walk(func(
+		route *mux.Route,
+		_ *mux.Router,
+		_ []*mux.Route,
+	) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("%s: route has no methods", tpl)
+
+			return nil
+		}
+
+		path := openapi.PathFromTemplate(tpl)
+
+		for _, method := range methods {
+			routes++
+
+			if _, ok := ops[openapi.OperationKey(method, path)]; !ok {
+				t.Errorf(
+					"%s %s is missing from the OpenAPI document",
+					method, path,
+				)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking routes: %v", err)
+	}
+
+	if routes != len(ops) {
+		t.Errorf(
+			"router has %d routes, document has %d operations",
+			routes, len(ops),
+		)
+	}
+}
+
+func TestInvalidUserCountsValidationFailures(t *testing.T) {
+	router, m := newTestRootHandler(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(
+		http.MethodPost,
+		"/api/users",
+		strings.NewReader(`{"name":"","email":"not an email"}`),
+	))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+
+	for _, field := range []string{"name", "email"} {
+		got := testutil.ToFloat64(
+			m.UserValidationFailuresTotal.WithLabelValues(field),
+		)
+		if got != 1 {
+			t.Errorf(
+				"user_validation_failures_total{field=%q} = %v, want 1",
+				field, got,
+			)
+		}
+	}
+}
+
+func TestUnmatchedRequestsReturnProblemWithRequestID(t *testing.T) {
+	router, _ := newTestRootHandler(t)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound},
+		{http.MethodGet, "/api/nope", http.StatusNotFound},
+		{http.MethodPatch, "/api/users", http.StatusMethodNotAllowed},
+		{"FOO", "/api/users", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(
+				rec,
+				httptest.NewRequest(tt.method, tt.path, nil),
+			)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+
+			var problem response.Problem
+
+			err := json.NewDecoder(rec.Body).Decode(&problem)
+			if err != nil {
+				t.Fatalf("decoding problem: %v", err)
+			}
+
+			requestID := rec.Header().Get(middleware.RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("response has no request ID header")
+			}
+
+			if problem.Instance != requestID {
+				t.Fatalf(
+					"problem instance = %q, want request ID %q",
+					problem.Instance, requestID,
+				)
+			}
+		})
+	}
+}
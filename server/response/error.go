@@ -1,9 +0,0 @@
-package response
-
-type Error struct {
-	Error string `json:"error"`
-}
-
-func NewError(message string) *Error {
-	return &Error{Error: message}
-}
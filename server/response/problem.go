@@ -0,0 +1,139 @@
+package response
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dzherb/mifi-go-microservice/openapi"
+	"github.com/dzherb/mifi-go-microservice/server/reqctx"
+)
+
+// ContentTypeProblem - тип содержимого ошибок по RFC 9457
+const ContentTypeProblem = "application/problem+json"
+
+const problemTypePrefix = "urn:mifi-go-microservice:problem:"
+
+// ProblemType - вид ошибки: клиент различает ошибки по URI,
+// а заголовок и статус у одного вида всегда одинаковые
+type ProblemType struct {
+	URI    string
+	Title  string
+	Status int
+}
+
+func newProblemType(name, title string, status int) ProblemType {
+	return ProblemType{
+		URI:    problemTypePrefix + name,
+		Title:  title,
+		Status: status,
+	}
+}
+
+var (
+	ProblemBadRequest = newProblemType(
+		"bad-request", "Bad request", http.StatusBadRequest,
+	)
+	ProblemValidation = newProblemType(
+		"validation-failed", "Request validation failed",
+		http.StatusBadRequest,
+	)
+	ProblemNotFound = newProblemType(
+		"not-found", "Resource not found", http.StatusNotFound,
+	)
+	ProblemMethodNotAllowed = newProblemType(
+		"method-not-allowed", "Method not allowed",
+		http.StatusMethodNotAllowed,
+	)
+	ProblemIdempotencyInProgress = newProblemType(
+		"idempotency-in-progress",
+		"Request with this idempotency key is in progress",
+		http.StatusConflict,
+	)
+	ProblemBodyTooLarge = newProblemType(
+		"body-too-large", "Request body too large",
+		http.StatusRequestEntityTooLarge,
+	)
+	ProblemMalformedBody = newProblemType(
+		"malformed-body", "Malformed request body",
+		http.StatusUnprocessableEntity,
+	)
+	ProblemIdempotencyKeyReused = newProblemType(
+		"idempotency-key-reused",
+		"Idempotency key was used with a different request",
+		http.StatusUnprocessableEntity,
+	)
+	ProblemRateLimited = newProblemType(
+		"rate-limited", "Too many requests", http.StatusTooManyRequests,
+	)
+	ProblemInternal = newProblemType(
+		"internal", "Internal server error",
+		http.StatusInternalServerError,
+	)
+	ProblemOverloaded = newProblemType(
+		"overloaded", "Server is overloaded",
+		http.StatusServiceUnavailable,
+	)
+)
+
+// Problem - тело ошибки по RFC 9457. Instance содержит идентификатор
+// запроса, по которому ошибку можно найти в логах, а Errors
+// перечисляет нарушения отдельных полей и параметров.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []openapi.Violation `json:"errors,omitempty"`
+}
+
+func NewProblem(t ProblemType, detail string) *Problem {
+	return &Problem{
+		Type:   t.URI,
+		Title:  t.Title,
+		Status: t.Status,
+		Detail: detail,
+	}
+}
+
+func NewBodyTooLargeProblem(limit int64) *Problem {
+	return NewProblem(
+		ProblemBodyTooLarge,
+		fmt.Sprintf("request body exceeds %d bytes", limit),
+	)
+}
+
+// NewValidationProblem перечисляет все нарушения схемы запроса сразу
+func NewValidationProblem(violations []openapi.Violation) *Problem {
+	p := NewProblem(
+		ProblemValidation,
+		"request does not match the API schema",
+	)
+	p.Errors = violations
+
+	return p
+}
+
+// WriteProblem пишет ошибку со статусом из p и идентификатором
+// запроса в instance
+func WriteProblem(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	p *Problem,
+) {
+	if p.Instance == "" {
+		p.Instance = reqctx.RequestID(r.Context())
+	}
+
+	write(w, log, ContentTypeProblem, p, p.Status)
+}
+
+func WriteDefaultError(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+) {
+	WriteProblem(w, r, log, NewProblem(ProblemInternal, "something went wrong"))
+}
@@ -7,7 +7,17 @@ import (
 )
 
 func Write(w http.ResponseWriter, log *slog.Logger, obj any, status int) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	write(w, log, "application/json; charset=utf-8", obj, status)
+}
+
+func write(
+	w http.ResponseWriter,
+	log *slog.Logger,
+	contentType string,
+	obj any,
+	status int,
+) {
+	w.Header().Set("Content-Type", contentType)
 
 	if status == 0 {
 		status = http.StatusOK
@@ -25,11 +35,3 @@ func Write(w http.ResponseWriter, log *slog.Logger, obj any, status int) {
 		}
 	}
 }
-
-func WriteDefaultError(w http.ResponseWriter, log *slog.Logger) {
-	Write(
-		w, log,
-		NewError("something went wrong"),
-		http.StatusInternalServerError,
-	)
-}
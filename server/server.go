@@ -1,34 +1,77 @@
 package server
 
 import (
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/openapi"
 	"github.com/dzherb/mifi-go-microservice/server/handler"
 	"github.com/dzherb/mifi-go-microservice/server/middleware"
 	"github.com/dzherb/mifi-go-microservice/service"
 )
 
+const serviceName = "mifi-go-microservice"
+
 type APIConfig struct {
-	MaxRequestsPerSecond int
-	MaxBurst             int
+	// MaxBodyBytes - максимальный размер тела запроса, 0 без ограничения;
+	// JSON тела, которые проверяются по документу OpenAPI, при этом
+	// все равно не больше 1 МБ
+	MaxBodyBytes int64
+
+	AccessLogSuccessSampleRate float64
+
+	// ValidateResponses сверяет ответы с документом OpenAPI и пишет
+	// расхождения в лог; нужно при разработке, в продакшене
+	// только добавляет копирование ответов
+	ValidateResponses bool
 }
 
 func RootHandler(
 	log *slog.Logger,
+	metrics *metric.Metrics,
 	userService *service.UserService,
 	notifier *service.Notifier,
+	idempotencyService *service.IdempotencyService,
+	rateLimiter *middleware.RateLimiter,
+	concurrencyLimiter *middleware.ConcurrencyLimiter,
 	cfg *APIConfig,
-) http.Handler {
+) (http.Handler, error) {
 	r := mux.NewRouter()
 
-	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	collectMetrics := middleware.CollectRequestsMetrics(metrics)
+
+	// mux применяет middleware только к совпавшим маршрутам, поэтому
+	// обработчики несуществующих путей и методов оборачиваются
+	// в эту же цепочку отдельно, чтобы у ответа был request ID,
+	// запись в access log и span, а сам ответ попал в метрики
+	requestScope := []mux.MiddlewareFunc{
+		otelmux.Middleware(serviceName),
+		middleware.RequestIDMiddleware(log),
+		middleware.ClientCertMiddleware,
+		middleware.AccessLogMiddleware(
+			log,
+			middleware.AccessLogConfig{
+				SuccessSampleRate: cfg.AccessLogSuccessSampleRate,
+				ExcludePaths:      []string{"/api/ping"},
+			},
+		),
+		// метрики собираются до ограничителей, чтобы ответы 413 и 503
+		// попадали в http_requests_total и в расчет SLO
+		collectMetrics,
+	}
+
+	r.Use(requestScope...)
+	r.Use(middleware.BodyLimitMiddleware(log, cfg.MaxBodyBytes))
+	r.Use(concurrencyLimiter.Middleware)
 
 	api := r.PathPrefix("/api").Subrouter()
 
@@ -38,7 +81,12 @@ func RootHandler(
 		http.HandlerFunc(pingHandler.Ping),
 	).Methods(http.MethodGet)
 
-	userHandler := handler.NewUserHandler(log, userService, notifier)
+	userHandler := handler.NewUserHandler(
+		log,
+		metrics,
+		userService,
+		notifier,
+	)
 	api.Handle(
 		"/users/{id}",
 		http.HandlerFunc(userHandler.Get),
@@ -57,30 +105,144 @@ func RootHandler(
 	).Methods(http.MethodGet)
 	api.Handle(
 		"/users",
-		http.HandlerFunc(userHandler.Create),
+		middleware.IdempotencyMiddleware(log, idempotencyService)(
+			http.HandlerFunc(userHandler.Create),
+		),
 	).Methods(http.MethodPost)
 
-	api.Use(middleware.CollectRequestsMetrics)
+	openAPIHandler := handler.NewOpenAPIHandler(log)
+	api.Handle(
+		"/openapi.json",
+		http.HandlerFunc(openAPIHandler.Spec),
+	).Methods(http.MethodGet)
+	api.Handle(
+		"/docs",
+		http.HandlerFunc(openAPIHandler.Docs),
+	).Methods(http.MethodGet)
+
+	api.Use(rateLimiter.Middleware)
+
+	// несовпавшие запросы попадают в метрики под одной меткой
+	notFound := withMiddleware(handler.NotFound(log), requestScope...)
+	methodNotAllowed := withMiddleware(
+		handler.MethodNotAllowed(log),
+		requestScope...,
+	)
+
+	// mux теряет ErrMethodMismatch, если после маршрута с тем же путём
+	// идут маршруты с другим путём, поэтому 405 определяется отдельно
+	r.NotFoundHandler = methodAwareNotFound(r, notFound, methodNotAllowed)
+	r.MethodNotAllowedHandler = methodNotAllowed
+
+	doc, err := openapi.Build(apiInfo, r, apiOperations())
+	if err != nil {
+		return nil, fmt.Errorf("building OpenAPI document: %w", err)
+	}
+
+	err = openAPIHandler.SetDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// middleware применяются при обработке запроса, поэтому
+	// проверку можно добавить после сборки документа
 	api.Use(
-		middleware.RateLimitMiddleware(
-			float64(cfg.MaxRequestsPerSecond),
-			cfg.MaxBurst,
+		middleware.OpenAPIValidationMiddleware(
+			log,
+			metrics,
+			doc,
+			middleware.OpenAPIValidationConfig{
+				ValidateResponses: cfg.ValidateResponses,
+				OnInvalidRequest:  countUserValidationFailures(metrics),
+			},
 		),
 	)
 
-	return r
+	return r, nil
+}
+
+// methodAwareNotFound отвечает 405, если путь запроса совпадает
+// с маршрутом под другим методом, и 404 в остальных случаях
+func methodAwareNotFound(
+	router *mux.Router,
+	notFound, methodNotAllowed http.Handler,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, method := range routeMethods {
+			if method == r.Method {
+				continue
+			}
+
+			alt := r.Clone(r.Context())
+			alt.Method = method
+
+			var match mux.RouteMatch
+			if router.Match(alt, &match) && match.MatchErr == nil {
+				methodNotAllowed.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		notFound.ServeHTTP(w, r)
+	})
+}
+
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// withMiddleware оборачивает h так же, как Router.Use: первый
+// middleware получает запрос первым
+func withMiddleware(h http.Handler, mws ...mux.MiddlewareFunc) http.Handler {
+	for _, mw := range slices.Backward(mws) {
+		h = mw(h)
+	}
+
+	return h
 }
 
 type Config struct {
 	Host              string
 	Port              int
 	ReadHeaderTimeout time.Duration
+	// ReadTimeout - время на чтение всего запроса вместе с телом
+	ReadTimeout time.Duration
+	// WriteTimeout отсчитывается от конца чтения заголовков
+	// до конца записи ответа
+	WriteTimeout time.Duration
+	// IdleTimeout - сколько держать keep-alive соединение без запросов
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// TLS включается, если заданы файлы сертификата и ключа;
+	// такой сервер запускается через ListenAndServeTLS("", "")
+	TLS TLSConfig
 }
 
-func New(h http.Handler, cfg Config) *http.Server {
-	return &http.Server{
+func New(log *slog.Logger, h http.Handler, cfg Config) (*http.Server, error) {
+	srv := &http.Server{
 		Addr:              net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
 		Handler:           h,
 		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
+
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := NewTLSConfig(log, cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("configuring TLS: %w", err)
+		}
+
+		srv.TLSConfig = tlsCfg
+	}
+
+	return srv, nil
 }
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/server/middleware"
+	"github.com/dzherb/mifi-go-microservice/slo"
+)
+
+// blockingStorage держит Get, пока не закрыт release, чтобы занять
+// единственный слот ограничителя конкурентности
+type blockingStorage struct {
+	nopStorage[model.User]
+
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStorage) Get(
+	ctx context.Context,
+	key string,
+) (model.User, error) {
+	s.started <- struct{}{}
+
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+	}
+
+	return s.nopStorage.Get(ctx, key)
+}
+
+func TestShedRequestsLowerAvailability(t *testing.T) {
+	users := blockingStorage{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	router, _, reg := newRootHandlerWith(
+		t,
+		users,
+		&APIConfig{MaxBodyBytes: 1 << 20},
+		middleware.ConcurrencyLimitConfig{
+			MaxInFlight:  1,
+			QueueTimeout: 50 * time.Millisecond,
+			RetryAfter:   time.Second,
+		},
+	)
+
+	var gathered atomic.Int64
+
+	tracker := slo.NewTracker(
+		slog.New(slog.DiscardHandler),
+		prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			defer gathered.Add(1)
+
+			return reg.Gather()
+		}),
+		[]slo.Objective{{
+			Name:             "get_user",
+			Method:           http.MethodGet,
+			Route:            "/api/users/{id}",
+			Availability:     0.99,
+			LatencyThreshold: time.Second,
+			LatencyTarget:    0.99,
+		}},
+		[]time.Duration{time.Hour},
+		5*time.Millisecond,
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go tracker.Run(ctx)
+
+	// первый снимок должен быть сделан до запросов
+	waitFor(t, func() bool { return gathered.Load() >= 1 })
+
+	target := "/api/users/" + uuid.NewString()
+	first := make(chan int)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		first <- rec.Code
+	}()
+
+	<-users.started
+
+	shed := httptest.NewRecorder()
+	router.ServeHTTP(shed, httptest.NewRequest(http.MethodGet, target, nil))
+
+	close(users.release)
+
+	if code := <-first; code != http.StatusNotFound {
+		t.Fatalf("first request status = %d, want 404", code)
+	}
+
+	if shed.Code != http.StatusServiceUnavailable {
+		t.Fatalf("shed request status = %d, want 503", shed.Code)
+	}
+
+	// снимок, начатый после обоих запросов, гарантированно
+	// сохранен, когда завершился следующий за ним
+	after := gathered.Load()
+	waitFor(t, func() bool { return gathered.Load() >= after+3 })
+
+	window := tracker.Summary()[0].Windows[0]
+
+	if window.Requests != 2 || window.Errors != 1 {
+		t.Fatalf(
+			"window has %v requests and %v errors, want 2 and 1",
+			window.Requests, window.Errors,
+		)
+	}
+
+	if window.AvailabilityBurnRate <= 0 {
+		t.Fatalf(
+			"availability burn rate = %v, want positive",
+			window.AvailabilityBurnRate,
+		)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
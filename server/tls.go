@@ -0,0 +1,266 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify_if_given"
+	ClientAuthRequireAndVerify = "require_and_verify"
+)
+
+// certCheckInterval - как часто при рукопожатии проверять,
+// не изменились ли файлы сертификата
+const certCheckInterval = 5 * time.Second
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion - 1.2 или 1.3, по умолчанию 1.2
+	MinVersion string
+	// CipherSuites - имена наборов шифров для TLS 1.2, например
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; в TLS 1.3 наборы
+	// не настраиваются
+	CipherSuites []string
+	// ClientCAFile - сертификаты УЦ для проверки клиентов; в отличие
+	// от сертификата сервера читается только при запуске, поэтому
+	// смена УЦ требует перезапуска
+	ClientCAFile string
+	// ClientAuth - none, request, require, verify_if_given или
+	// require_and_verify; при заданном ClientCAFile по умолчанию
+	// require_and_verify
+	ClientAuth string
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// NewTLSConfig собирает конфигурацию TLS с перечитыванием сертификата;
+// используется и HTTP, и gRPC сервером. Ошибки перечитывания пишутся
+// в log, сервер при этом продолжает отдавать прежний сертификат.
+func NewTLSConfig(log *slog.Logger, cfg TLSConfig) (*tls.Config, error) {
+	var errs []error
+
+	minVersion, err := parseTLSVersion(cfg.MinVersion)
+	errs = append(errs, err)
+
+	cipherSuites, err := parseCipherSuites(cfg.CipherSuites)
+	errs = append(errs, err)
+
+	clientAuth, err := parseClientAuth(cfg.ClientAuth, cfg.ClientCAFile)
+	errs = append(errs, err)
+
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	certs, err := newCertReloader(log, cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"no certificates found in %s",
+				cfg.ClientCAFile,
+			)
+		}
+
+		tlsCfg.ClientCAs = pool
+	}
+
+	return tlsCfg, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min version %q", version)
+	}
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+
+	var errs []error
+
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown cipher suite %q", name))
+
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, errors.Join(errs...)
+}
+
+func parseClientAuth(mode, caFile string) (tls.ClientAuthType, error) {
+	if mode == "" {
+		if caFile != "" {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+
+		return tls.NoClientCert, nil
+	}
+
+	modes := map[string]tls.ClientAuthType{
+		ClientAuthNone:             tls.NoClientCert,
+		ClientAuthRequest:          tls.RequestClientCert,
+		ClientAuthRequire:          tls.RequireAnyClientCert,
+		ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+		ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+	}
+
+	clientAuth, ok := modes[mode]
+	if !ok {
+		return 0, fmt.Errorf("unknown client auth mode %q", mode)
+	}
+
+	if clientAuth >= tls.VerifyClientCertIfGiven && caFile == "" {
+		return 0, fmt.Errorf("client auth mode %q requires client CA", mode)
+	}
+
+	return clientAuth, nil
+}
+
+// certReloader перечитывает сертификат, когда на диске меняются
+// файлы, поэтому обновленный сертификат подхватывается без
+// перезапуска; при ошибке чтения остается прежний
+type certReloader struct {
+	log      *slog.Logger
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	checkedAt   time.Time
+	// lastErr - ошибка последней проверки файлов, чтобы писать
+	// в лог только ее изменения, а не каждую проверку
+	lastErr error
+}
+
+func newCertReloader(
+	log *slog.Logger,
+	certFile string,
+	keyFile string,
+) (*certReloader, error) {
+	r := &certReloader{log: log, certFile: certFile, keyFile: keyFile}
+
+	err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(
+	*tls.ClientHelloInfo,
+) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= certCheckInterval {
+		r.check()
+	}
+
+	return r.cert, nil
+}
+
+// check перечитывает файлы; ошибка не прерывает рукопожатие:
+// отдается прежний сертификат, пока файлы не станут согласованными
+func (r *certReloader) check() {
+	err := r.reload()
+
+	switch {
+	case err != nil && (r.lastErr == nil || err.Error() != r.lastErr.Error()):
+		r.log.Error(
+			"failed to reload TLS certificate, serving the previous one",
+			slog.String("cert_file", r.certFile),
+			slog.String("key_file", r.keyFile),
+			slog.String("error", err.Error()),
+		)
+	case err == nil && r.lastErr != nil:
+		r.log.Info(
+			"TLS certificate reloaded after failure",
+			slog.String("cert_file", r.certFile),
+		)
+	}
+
+	r.lastErr = err
+}
+
+func (r *certReloader) reload() error {
+	r.checkedAt = time.Now()
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("checking certificate file: %w", err)
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("checking key file: %w", err)
+	}
+
+	if r.cert != nil &&
+		certInfo.ModTime().Equal(r.certModTime) &&
+		keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dzherb/mifi-go-microservice/model"
+)
+
+type UserEventType string
+
+const (
+	UserCreated UserEventType = "created"
+	UserUpdated UserEventType = "updated"
+	UserDeleted UserEventType = "deleted"
+)
+
+type UserEvent struct {
+	Type UserEventType
+	// User у события удаления содержит только ID
+	User model.User
+}
+
+// userEventsBufferSize - сколько событий может накопиться у медленного
+// подписчика, прежде чем новые начнут для него отбрасываться
+const userEventsBufferSize = 64
+
+// eventBroker рассылает события подписчикам внутри процесса;
+// публикация никогда не блокируется на медленном подписчике
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan UserEvent]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[chan UserEvent]struct{})}
+}
+
+func (b *eventBroker) subscribe(ctx context.Context) <-chan UserEvent {
+	ch := make(chan UserEvent, userEventsBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[ch] = struct{}{}
+
+	context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	})
+
+	return ch
+}
+
+// publish возвращает число подписчиков, которым событие
+// не досталось из-за переполненного буфера
+func (b *eventBroker) publish(event UserEvent) (dropped int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			dropped++
+		}
+	}
+
+	return dropped
+}
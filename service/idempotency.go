@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/storage"
+)
+
+var (
+	ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+)
+
+type IdempotencyService struct {
+	log     *slog.Logger
+	storage Storage[model.IdempotencyRecord]
+	ttl     time.Duration
+}
+
+func NewIdempotencyService(
+	log *slog.Logger,
+	storage Storage[model.IdempotencyRecord],
+	ttl time.Duration,
+) *IdempotencyService {
+	return &IdempotencyService{
+		log:     log,
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+func (s *IdempotencyService) Get(
+	ctx context.Context,
+	key string,
+) (model.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
+	defer cancel()
+
+	record, err := s.storage.Get(ctx, s.buildStorageKey(key))
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return record, ErrIdempotencyRecordNotFound
+		}
+
+		return record, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	// MinIO не удаляет объекты по TTL сам, поэтому просроченная запись
+	// удаляется при чтении и считается отсутствующей
+	if time.Now().After(record.ExpiresAt) {
+		err = s.storage.Delete(ctx, s.buildStorageKey(key))
+		if err != nil {
+			logger.FromContext(ctx, s.log).WarnContext(
+				ctx,
+				"failed to delete expired idempotency record",
+				slog.String("error", err.Error()),
+			)
+		}
+
+		return model.IdempotencyRecord{}, ErrIdempotencyRecordNotFound
+	}
+
+	return record, nil
+}
+
+func (s *IdempotencyService) Save(
+	ctx context.Context,
+	record model.IdempotencyRecord,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
+	defer cancel()
+
+	now := time.Now()
+	record.CreatedAt = now
+	record.ExpiresAt = now.Add(s.ttl)
+
+	err := s.storage.Set(ctx, s.buildStorageKey(record.Key), record)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (s *IdempotencyService) buildStorageKey(key string) string {
+	// ключ приходит от клиента, поэтому хешируется перед
+	// использованием в качестве имени объекта
+	sum := sha256.Sum256([]byte(key))
+
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
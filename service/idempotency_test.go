@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/dzherb/mifi-go-microservice/model"
+	"github.com/dzherb/mifi-go-microservice/storage"
+)
+
+type memStorage[T any] struct {
+	items map[string]T
+}
+
+func (s *memStorage[T]) Set(_ context.Context, key string, v T) error {
+	if s.items == nil {
+		s.items = make(map[string]T)
+	}
+
+	s.items[key] = v
+
+	return nil
+}
+
+func (s *memStorage[T]) Get(_ context.Context, key string) (T, error) {
+	v, ok := s.items[key]
+	if !ok {
+		return v, storage.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+func (s *memStorage[T]) GetAll(context.Context) ([]T, error) {
+	all := make([]T, 0, len(s.items))
+	for _, v := range s.items {
+		all = append(all, v)
+	}
+
+	return all, nil
+}
+
+func (s *memStorage[T]) Count(context.Context) (int, error) {
+	return len(s.items), nil
+}
+
+func (s *memStorage[T]) Delete(_ context.Context, key string) error {
+	delete(s.items, key)
+
+	return nil
+}
+
+func TestIdempotencyServiceDeletesExpiredRecord(t *testing.T) {
+	store := &memStorage[model.IdempotencyRecord]{}
+	svc := NewIdempotencyService(
+		slog.New(slog.DiscardHandler),
+		store,
+		-time.Second,
+	)
+
+	err := svc.Save(t.Context(), model.IdempotencyRecord{Key: "k"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	_, err = svc.Get(t.Context(), "k")
+	if !errors.Is(err, ErrIdempotencyRecordNotFound) {
+		t.Fatalf("Get expired record error = %v, want not found", err)
+	}
+
+	if len(store.items) != 0 {
+		t.Fatalf("expired record was not deleted, %d left", len(store.items))
+	}
+}
+
+func TestIdempotencyServiceKeepsLiveRecord(t *testing.T) {
+	store := &memStorage[model.IdempotencyRecord]{}
+	svc := NewIdempotencyService(
+		slog.New(slog.DiscardHandler),
+		store,
+		time.Hour,
+	)
+
+	err := svc.Save(t.Context(), model.IdempotencyRecord{Key: "k"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	record, err := svc.Get(t.Context(), "k")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if record.Key != "k" || len(store.items) != 1 {
+		t.Fatalf("live record = %+v, stored %d", record, len(store.items))
+	}
+}
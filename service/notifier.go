@@ -1,22 +1,204 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/tracing"
+)
+
+var (
+	ErrNotifierQueueFull   = errors.New("notification queue is full")
+	ErrNotifierQueueClosed = errors.New("notification queue is closed")
 )
 
+type NotifierConfig struct {
+	// WebhookURLs - адреса, на которые отправляются уведомления;
+	// если список пуст, отправка только имитируется
+	WebhookURLs []string
+	Timeout     time.Duration
+	QueueSize   int
+	Workers     int
+}
+
+type notification struct {
+	ctx   context.Context
+	msg   string
+	extra map[string]any
+}
+
 type Notifier struct {
-	log *slog.Logger
+	log     *slog.Logger
+	metrics *metric.Metrics
+	cfg     NotifierConfig
+	client  *http.Client
+
+	mu      sync.RWMutex
+	closed  bool
+	targets []string
+	queue   chan notification
+	wg      sync.WaitGroup
 }
 
-func NewNotifier(log *slog.Logger) *Notifier {
+func NewNotifier(
+	log *slog.Logger,
+	metrics *metric.Metrics,
+	cfg NotifierConfig,
+) *Notifier {
 	return &Notifier{
-		log: log,
+		log:     log,
+		metrics: metrics,
+		cfg:     cfg,
+		client: &http.Client{
+			// транспорт передает W3C trace context в заголовках вебхука
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   cfg.Timeout,
+		},
+		targets: cfg.WebhookURLs,
+		queue:   make(chan notification, cfg.QueueSize),
+	}
+}
+
+// SetTargets заменяет адреса вебхуков; уведомления, которые уже
+// отправляются, доставляются по старым адресам
+func (n *Notifier) SetTargets(webhookURLs []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.targets = webhookURLs
+}
+
+func (n *Notifier) webhookURLs() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.targets
+}
+
+// Start запускает воркеры, разбирающие очередь уведомлений
+func (n *Notifier) Start() {
+	for range max(n.cfg.Workers, 1) {
+		n.wg.Go(func() {
+			for item := range n.queue {
+				n.deliver(item.ctx, item.msg, item.extra)
+			}
+		})
+	}
+}
+
+// Close перестает принимать уведомления и ждет отправки уже
+// поставленных в очередь, но не дольше, чем живет ctx
+func (n *Notifier) Close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf(
+			"notifier closed with %d pending notifications: %w",
+			len(n.queue),
+			ctx.Err(),
+		)
 	}
 }
 
-func (n *Notifier) Send(msg string, extra map[string]any) {
+// Send ставит уведомление в очередь и не блокируется;
+// при переполненной очереди уведомление отбрасывается
+func (n *Notifier) Send(ctx context.Context, msg string, extra map[string]any) {
+	err := n.enqueue(notification{ctx: ctx, msg: msg, extra: extra})
+	if err != nil {
+		n.metrics.NotificationsTotal.WithLabelValues(msg, "dropped").Inc()
+
+		logger.FromContext(ctx, n.log).WarnContext(
+			ctx,
+			"notification dropped",
+			slog.String("message", msg),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (n *Notifier) enqueue(item notification) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		return ErrNotifierQueueClosed
+	}
+
+	select {
+	case n.queue <- item:
+		return nil
+	default:
+		return ErrNotifierQueueFull
+	}
+}
+
+func (n *Notifier) QueueDepth() int {
+	return len(n.queue)
+}
+
+// CheckQueue сообщает об ошибке, когда очередь почти заполнена
+// и новые уведомления вот-вот начнут отбрасываться
+func (n *Notifier) CheckQueue(context.Context) error {
+	depth, capacity := len(n.queue), cap(n.queue)
+	if capacity == 0 {
+		return nil
+	}
+
+	if float64(depth) >= float64(capacity)*notifierQueueHighWatermark {
+		return fmt.Errorf(
+			"notification queue depth %d of %d",
+			depth,
+			capacity,
+		)
+	}
+
+	return nil
+}
+
+const notifierQueueHighWatermark = 0.9
+
+func (n *Notifier) deliver(
+	ctx context.Context,
+	msg string,
+	extra map[string]any,
+) {
+	var err error
+
+	ctx, span := tracer.Start(ctx, "Notifier.Send")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	span.SetAttributes(attribute.String("notification.message", msg))
+
+	log := logger.FromContext(ctx, n.log)
+
 	if extra == nil {
 		extra = make(map[string]any)
 	}
@@ -26,19 +208,91 @@ func (n *Notifier) Send(msg string, extra map[string]any) {
 
 	notificationData, err := json.Marshal(extra)
 	if err != nil {
-		n.log.Error(
+		log.ErrorContext(
+			ctx,
 			"error marshaling notification",
 			slog.String("error", err.Error()),
 		)
 
+		n.metrics.NotificationsTotal.WithLabelValues(msg, "invalid").Inc()
+
 		return
 	}
 
-	// imitate sending the notification
-	time.Sleep(100 * time.Millisecond)
+	webhookURLs := n.webhookURLs()
+
+	if len(webhookURLs) == 0 {
+		// imitate sending the notification
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	errs := make([]error, 0, len(webhookURLs))
+
+	for _, url := range webhookURLs {
+		errs = append(errs, n.postWebhook(ctx, url, notificationData))
+	}
+
+	err = errors.Join(errs...)
+	if err != nil {
+		log.ErrorContext(
+			ctx,
+			"failed to send notification",
+			slog.String("error", err.Error()),
+		)
 
-	n.log.Info(
+		n.metrics.NotificationsTotal.WithLabelValues(msg, "failed").Inc()
+
+		return
+	}
+
+	n.metrics.NotificationsTotal.WithLabelValues(msg, "sent").Inc()
+
+	log.InfoContext(
+		ctx,
 		"notification sent",
 		slog.String("payload", string(notificationData)),
 	)
 }
+
+// maxDrainedBytes - сколько байт ответа вебхука дочитывается, чтобы
+// переиспользовать соединение; большие ответы дешевле закрыть
+const maxDrainedBytes = 64 << 10
+
+func (n *Notifier) postWebhook(
+	ctx context.Context,
+	url string,
+	payload []byte,
+) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		url,
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("building webhook request to %s: %w", url, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending webhook to %s: %w", url, err)
+	}
+
+	defer func() {
+		// без дочитывания тела соединение не вернется в пул keep-alive
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBytes))
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf(
+			"webhook %s responded with status %d",
+			url,
+			resp.StatusCode,
+		)
+	}
+
+	return nil
+}
@@ -8,37 +8,73 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
 	"github.com/dzherb/mifi-go-microservice/model"
 	"github.com/dzherb/mifi-go-microservice/storage"
+	"github.com/dzherb/mifi-go-microservice/tracing"
 )
 
 var (
 	ErrUserDoesNotExist = errors.New("user does not exist")
 )
 
+var tracer = otel.Tracer("github.com/dzherb/mifi-go-microservice/service")
+
 type Storage[T any] interface {
 	Set(context.Context, string, T) error
 	Get(context.Context, string) (T, error)
 	GetAll(context.Context) ([]T, error)
+	Count(context.Context) (int, error)
 	Delete(context.Context, string) error
 }
 
 type UserService struct {
 	log     *slog.Logger
+	metrics *metric.Metrics
 	storage Storage[model.User]
+	events  *eventBroker
 }
 
 func NewUserService(
 	log *slog.Logger,
+	metrics *metric.Metrics,
 	storage Storage[model.User],
 ) *UserService {
 	return &UserService{
 		log:     log,
+		metrics: metrics,
 		storage: storage,
+		events:  newEventBroker(),
 	}
 }
 
+// Subscribe возвращает канал с изменениями пользователей, сделанными
+// после подписки. Канал закрывается при отмене ctx; если подписчик
+// не успевает читать, события для него отбрасываются.
+func (u *UserService) Subscribe(ctx context.Context) <-chan UserEvent {
+	return u.events.subscribe(ctx)
+}
+
+func (u *UserService) publish(ctx context.Context, event UserEvent) {
+	dropped := u.events.publish(event)
+	if dropped == 0 {
+		return
+	}
+
+	u.metrics.UserEventsDroppedTotal.Add(float64(dropped))
+
+	logger.FromContext(ctx, u.log).WarnContext(
+		ctx,
+		"user event dropped for slow subscribers",
+		slog.String("event", string(event.Type)),
+		slog.Int("subscribers", dropped),
+	)
+}
+
 var (
 	ErrMissingUserID = errors.New("missing user ID")
 )
@@ -48,7 +84,10 @@ const userOperationsTimeout = 10 * time.Second
 func (u *UserService) Create(
 	ctx context.Context,
 	user model.User,
-) (model.User, error) {
+) (_ model.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Create")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
 
 	defer cancel()
@@ -56,15 +95,34 @@ func (u *UserService) Create(
 	createdUser := user
 	createdUser.ID = u.generateID()
 
-	err := u.storage.Set(ctx, u.buildStorageKey(createdUser.ID), createdUser)
+	span.SetAttributes(attribute.String("user.id", createdUser.ID))
+
+	err = u.storage.Set(ctx, u.buildStorageKey(createdUser.ID), createdUser)
 	if err != nil {
 		return user, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	u.metrics.UserOperationsTotal.WithLabelValues("create").Inc()
+	u.publish(ctx, UserEvent{Type: UserCreated, User: createdUser})
+
+	logger.FromContext(ctx, u.log).InfoContext(
+		ctx,
+		"user created",
+		slog.String("user_id", createdUser.ID),
+	)
+
 	return createdUser, nil
 }
 
-func (u *UserService) Get(ctx context.Context, id string) (model.User, error) {
+func (u *UserService) Get(
+	ctx context.Context,
+	id string,
+) (_ model.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Get")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	span.SetAttributes(attribute.String("user.id", id))
+
 	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
 
 	defer cancel()
@@ -81,7 +139,10 @@ func (u *UserService) Get(ctx context.Context, id string) (model.User, error) {
 	return user, nil
 }
 
-func (u *UserService) GetAll(ctx context.Context) ([]model.User, error) {
+func (u *UserService) GetAll(ctx context.Context) (_ []model.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetAll")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
 
 	defer cancel()
@@ -91,10 +152,17 @@ func (u *UserService) GetAll(ctx context.Context) ([]model.User, error) {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
 
+	span.SetAttributes(attribute.Int("users.count", len(users)))
+
 	return users, nil
 }
 
-func (u *UserService) Update(ctx context.Context, user model.User) error {
+func (u *UserService) Update(ctx context.Context, user model.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Update")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	span.SetAttributes(attribute.String("user.id", user.ID))
+
 	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
 
 	defer cancel()
@@ -103,7 +171,7 @@ func (u *UserService) Update(ctx context.Context, user model.User) error {
 		return ErrMissingUserID
 	}
 
-	_, err := u.Get(ctx, user.ID)
+	_, err = u.Get(ctx, user.ID)
 	if err != nil {
 		if errors.Is(err, storage.ErrKeyNotFound) {
 			return ErrUserDoesNotExist
@@ -117,21 +185,112 @@ func (u *UserService) Update(ctx context.Context, user model.User) error {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	u.metrics.UserOperationsTotal.WithLabelValues("update").Inc()
+	u.publish(ctx, UserEvent{Type: UserUpdated, User: user})
+
+	logger.FromContext(ctx, u.log).InfoContext(
+		ctx,
+		"user updated",
+		slog.String("user_id", user.ID),
+	)
+
 	return nil
 }
 
-func (u *UserService) Delete(ctx context.Context, id string) error {
+func (u *UserService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Delete")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	span.SetAttributes(attribute.String("user.id", id))
+
 	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
 	defer cancel()
 
-	err := u.storage.Delete(ctx, u.buildStorageKey(id))
+	// RemoveObject не сообщает об отсутствии объекта, поэтому
+	// существование проверяется заранее, иначе подписчики получили бы
+	// событие об удалении несуществующего пользователя
+	_, err = u.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = u.storage.Delete(ctx, u.buildStorageKey(id))
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	u.metrics.UserOperationsTotal.WithLabelValues("delete").Inc()
+	u.publish(ctx, UserEvent{Type: UserDeleted, User: model.User{ID: id}})
+
+	logger.FromContext(ctx, u.log).InfoContext(
+		ctx,
+		"user deleted",
+		slog.String("user_id", id),
+	)
+
 	return nil
 }
 
+func (u *UserService) Count(ctx context.Context) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Count")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, userOperationsTimeout)
+	defer cancel()
+
+	count, err := u.storage.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+const defaultUsersCountRefreshInterval = 30 * time.Second
+
+// WatchUsersCount периодически пересчитывает пользователей в хранилище
+// и обновляет метрику users_total, пока не отменен ctx
+func (u *UserService) WatchUsersCount(
+	ctx context.Context,
+	interval time.Duration,
+) {
+	// time.NewTicker паникует на неположительном интервале
+	if interval <= 0 {
+		u.log.WarnContext(
+			ctx,
+			"invalid users count refresh interval, using default",
+			slog.Duration("interval", interval),
+			slog.Duration("default", defaultUsersCountRefreshInterval),
+		)
+
+		interval = defaultUsersCountRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := u.Count(ctx)
+		if err != nil && ctx.Err() == nil {
+			u.log.ErrorContext(
+				ctx,
+				"failed to refresh users count",
+				slog.String("error", err.Error()),
+			)
+		}
+
+		if err == nil {
+			u.metrics.UsersTotal.Set(float64(count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (u *UserService) generateID() string {
 	return uuid.New().String()
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/model"
+)
+
+func TestUserServiceDeleteMissingUser(t *testing.T) {
+	m := metric.New(prometheus.NewRegistry())
+	svc := NewUserService(
+		slog.New(slog.DiscardHandler),
+		m,
+		&memStorage[model.User]{},
+	)
+
+	events := svc.Subscribe(t.Context())
+
+	err := svc.Delete(t.Context(), "missing")
+	if !errors.Is(err, ErrUserDoesNotExist) {
+		t.Fatalf("Delete error = %v, want %v", err, ErrUserDoesNotExist)
+	}
+
+	deletes := testutil.ToFloat64(
+		m.UserOperationsTotal.WithLabelValues("delete"),
+	)
+	if deletes != 0 {
+		t.Fatalf("delete operations = %v, want 0", deletes)
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestUserServiceDeleteExistingUser(t *testing.T) {
+	m := metric.New(prometheus.NewRegistry())
+	store := &memStorage[model.User]{}
+	svc := NewUserService(slog.New(slog.DiscardHandler), m, store)
+
+	user, err := svc.Create(t.Context(), model.User{Name: "a"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	events := svc.Subscribe(t.Context())
+
+	err = svc.Delete(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if len(store.items) != 0 {
+		t.Fatalf("user was not deleted, %d left", len(store.items))
+	}
+
+	deletes := testutil.ToFloat64(
+		m.UserOperationsTotal.WithLabelValues("delete"),
+	)
+	if deletes != 1 {
+		t.Fatalf("delete operations = %v, want 1", deletes)
+	}
+
+	select {
+	case event := <-events:
+		if event.Type != UserDeleted || event.User.ID != user.ID {
+			t.Fatalf("event = %+v, want deletion of %s", event, user.ID)
+		}
+	default:
+		t.Fatal("no deletion event published")
+	}
+}
@@ -0,0 +1,150 @@
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Objective описывает цели доступности и задержки для одного маршрута
+type Objective struct {
+	Name string
+	// Method - HTTP метод, пустой означает любой
+	Method string
+	// Route - шаблон маршрута, как в метке endpoint
+	Route string
+	// Availability - доля запросов без ошибок сервера, например 0.999
+	Availability float64
+	// LatencyThreshold округляется вниз до ближайшей границы бакета
+	// гистограммы http_request_duration_seconds
+	LatencyThreshold time.Duration
+	// LatencyTarget - доля запросов быстрее LatencyThreshold
+	LatencyTarget float64
+}
+
+func (o Objective) Validate() error {
+	var errs []error
+
+	if o.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+
+	if o.Route == "" {
+		errs = append(errs, errors.New("route is required"))
+	}
+
+	if o.Method != "" && !knownMethod(o.Method) {
+		errs = append(errs, fmt.Errorf("unknown method %q", o.Method))
+	}
+
+	if o.Availability <= 0 || o.Availability >= 1 {
+		errs = append(errs, errors.New("availability must be in (0, 1)"))
+	}
+
+	if o.LatencyThreshold <= 0 {
+		errs = append(errs, errors.New("latency threshold must be positive"))
+	}
+
+	if o.LatencyTarget <= 0 || o.LatencyTarget >= 1 {
+		errs = append(errs, errors.New("latency target must be in (0, 1)"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("objective %q: %w", o.Name, err)
+	}
+
+	return nil
+}
+
+// knownMethod ограничивает метод теми, что попадают в метку method
+// http_requests_total; с другим методом цель никогда не совпадет
+func knownMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodConnect,
+		http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseObjectives разбирает список целей в формате
+// name=METHOD /route:availability:latency_threshold:latency_target,
+// разделенных точкой с запятой, например
+// get_user=GET /api/users/{id}:0.999:250ms:0.99
+func ParseObjectives(s string) ([]Objective, error) {
+	var (
+		objectives []Objective
+		errs       []error
+	)
+
+	for item := range strings.SplitSeq(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		objective, err := parseObjective(item)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		objectives = append(objectives, objective)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return objectives, nil
+}
+
+func parseObjective(s string) (Objective, error) {
+	name, spec, ok := strings.Cut(s, "=")
+	if !ok {
+		return Objective{}, fmt.Errorf("objective %q: missing name", s)
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) != 4 {
+		return Objective{}, fmt.Errorf(
+			"objective %q: expected route:availability:latency:target",
+			name,
+		)
+	}
+
+	objective := Objective{Name: strings.TrimSpace(name)}
+
+	route := strings.TrimSpace(parts[0])
+	if method, path, ok := strings.Cut(route, " "); ok {
+		objective.Method = method
+		objective.Route = strings.TrimSpace(path)
+	} else {
+		objective.Route = route
+	}
+
+	var err error
+
+	objective.Availability, err = strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Objective{}, fmt.Errorf("objective %q: %w", name, err)
+	}
+
+	objective.LatencyThreshold, err = time.ParseDuration(parts[2])
+	if err != nil {
+		return Objective{}, fmt.Errorf("objective %q: %w", name, err)
+	}
+
+	objective.LatencyTarget, err = strconv.ParseFloat(parts[3], 64)
+	if err != nil {
+		return Objective{}, fmt.Errorf("objective %q: %w", name, err)
+	}
+
+	return objective, objective.Validate()
+}
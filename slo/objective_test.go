@@ -0,0 +1,141 @@
+package slo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseObjectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Objective
+		wantErr []string
+	}{
+		{
+			name:  "empty",
+			input: " ; ",
+		},
+		{
+			name:  "with method",
+			input: "get_user=GET /api/users/{id}:0.999:250ms:0.99",
+			want: []Objective{{
+				Name:             "get_user",
+				Method:           "GET",
+				Route:            "/api/users/{id}",
+				Availability:     0.999,
+				LatencyThreshold: 250 * time.Millisecond,
+				LatencyTarget:    0.99,
+			}},
+		},
+		{
+			name: "any method and several items",
+			input: " users=/api/users:0.99:1s:0.9 ;" +
+				" ping=/api/ping:0.9:10ms:0.5;",
+			want: []Objective{
+				{
+					Name:             "users",
+					Route:            "/api/users",
+					Availability:     0.99,
+					LatencyThreshold: time.Second,
+					LatencyTarget:    0.9,
+				},
+				{
+					Name:             "ping",
+					Route:            "/api/ping",
+					Availability:     0.9,
+					LatencyThreshold: 10 * time.Millisecond,
+					LatencyTarget:    0.5,
+				},
+			},
+		},
+		{
+			name:    "missing name",
+			input:   "GET /api/users:0.99:1s:0.9",
+			wantErr: []string{"missing name"},
+		},
+		{
+			name:    "wrong number of parts",
+			input:   "users=/api/users:0.99:1s",
+			wantErr: []string{"expected route:availability:latency:target"},
+		},
+		{
+			name:    "availability is not a number",
+			input:   "users=/api/users:high:1s:0.9",
+			wantErr: []string{`parsing "high"`},
+		},
+		{
+			name:    "bad duration",
+			input:   "users=/api/users:0.99:fast:0.9",
+			wantErr: []string{`invalid duration "fast"`},
+		},
+		{
+			name:    "availability of one",
+			input:   "users=/api/users:1:1s:0.9",
+			wantErr: []string{"availability must be in (0, 1)"},
+		},
+		{
+			name:    "zero availability",
+			input:   "users=/api/users:0:1s:0.9",
+			wantErr: []string{"availability must be in (0, 1)"},
+		},
+		{
+			name:    "latency target above one",
+			input:   "users=/api/users:0.99:1s:1.5",
+			wantErr: []string{"latency target must be in (0, 1)"},
+		},
+		{
+			name:    "non-positive threshold",
+			input:   "users=/api/users:0.99:0s:0.9",
+			wantErr: []string{"latency threshold must be positive"},
+		},
+		{
+			name:    "unknown method",
+			input:   "users=FETCH /api/users:0.99:1s:0.9",
+			wantErr: []string{`unknown method "FETCH"`},
+		},
+		{
+			name:    "lowercase method",
+			input:   "users=get /api/users:0.99:1s:0.9",
+			wantErr: []string{`unknown method "get"`},
+		},
+		{
+			name:  "errors of all items are joined",
+			input: "a=/a:2:1s:0.9;b=/b:0.9:1s:0.9;c=/c:0.9:1s",
+			wantErr: []string{
+				`objective "a"`,
+				`objective "c"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseObjectives(tt.input)
+
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("ParseObjectives() = %+v, want error", got)
+				}
+
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not contain %q", err, want)
+					}
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseObjectives() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseObjectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	requestsMetric = "http_requests_total"
+	durationMetric = "http_request_duration_seconds"
+
+	sliAvailability = "availability"
+	sliLatency      = "latency"
+)
+
+var DefaultWindows = []time.Duration{
+	5 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+type counts struct {
+	Total  float64
+	Errors float64
+	Slow   float64
+}
+
+func (c counts) sub(other counts) counts {
+	return counts{
+		Total:  c.Total - other.Total,
+		Errors: c.Errors - other.Errors,
+		Slow:   c.Slow - other.Slow,
+	}
+}
+
+type snapshot struct {
+	at     time.Time
+	counts []counts
+}
+
+// Tracker периодически снимает значения счетчиков запросов
+// из реестра метрик и по разнице между снимками считает,
+// с какой скоростью расходуется бюджет ошибок в каждом окне
+type Tracker struct {
+	log        *slog.Logger
+	gatherer   prometheus.Gatherer
+	objectives []Objective
+	windows    []time.Duration
+	interval   time.Duration
+
+	mu        sync.RWMutex
+	snapshots []snapshot
+
+	burnRateDesc *prometheus.Desc
+}
+
+func NewTracker(
+	log *slog.Logger,
+	gatherer prometheus.Gatherer,
+	objectives []Objective,
+	windows []time.Duration,
+	interval time.Duration,
+) *Tracker {
+	if len(windows) == 0 {
+		windows = DefaultWindows
+	}
+
+	return &Tracker{
+		log:        log,
+		gatherer:   gatherer,
+		objectives: objectives,
+		windows:    windows,
+		interval:   interval,
+		burnRateDesc: prometheus.NewDesc(
+			"slo_error_budget_burn_rate",
+			"Error budget burn rate of the objective over the window",
+			[]string{"slo", "sli", "window"},
+			nil,
+		),
+	}
+}
+
+// Run снимает показатели раз в interval, пока не отменен ctx
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		err := t.sample(time.Now())
+		if err != nil {
+			t.log.Error(
+				"failed to sample slo metrics",
+				slog.String("error", err.Error()),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Tracker) sample(now time.Time) error {
+	families, err := t.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gathering metrics: %w", err)
+	}
+
+	current := snapshot{
+		at:     now,
+		counts: make([]counts, len(t.objectives)),
+	}
+
+	for _, family := range families {
+		switch family.GetName() {
+		case requestsMetric:
+			t.collectRequests(family, current.counts)
+		case durationMetric:
+			t.collectDurations(family, current.counts)
+		}
+	}
+
+	maxWindow := t.windows[0]
+	for _, window := range t.windows {
+		maxWindow = max(maxWindow, window)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.snapshots = append(t.snapshots, current)
+
+	// самый старый снимок нужен как точка отсчета для самого
+	// длинного окна, остальные можно выбросить
+	cutoff := now.Add(-maxWindow - t.interval)
+	for len(t.snapshots) > 1 && t.snapshots[1].at.Before(cutoff) {
+		t.snapshots = t.snapshots[1:]
+	}
+
+	return nil
+}
+
+func (t *Tracker) collectRequests(family *dto.MetricFamily, dst []counts) {
+	for _, m := range family.GetMetric() {
+		labels := labelsMap(m)
+
+		for i, objective := range t.objectives {
+			if !objective.matches(labels) {
+				continue
+			}
+
+			value := m.GetCounter().GetValue()
+			dst[i].Total += value
+
+			if isServerError(labels["status"]) {
+				dst[i].Errors += value
+			}
+		}
+	}
+}
+
+func (t *Tracker) collectDurations(family *dto.MetricFamily, dst []counts) {
+	for _, m := range family.GetMetric() {
+		labels := labelsMap(m)
+
+		for i, objective := range t.objectives {
+			if !objective.matches(labels) {
+				continue
+			}
+
+			histogram := m.GetHistogram()
+			fast := fastCount(histogram, objective.LatencyThreshold)
+
+			dst[i].Slow += float64(histogram.GetSampleCount()) - fast
+		}
+	}
+}
+
+// fastCount возвращает число наблюдений в наибольшем бакете,
+// граница которого не превышает порог
+func fastCount(histogram *dto.Histogram, threshold time.Duration) float64 {
+	var fast float64
+
+	for _, bucket := range histogram.GetBucket() {
+		if bucket.GetUpperBound() > threshold.Seconds() {
+			break
+		}
+
+		fast = float64(bucket.GetCumulativeCount())
+	}
+
+	return fast
+}
+
+func (o Objective) matches(labels map[string]string) bool {
+	if labels["endpoint"] != o.Route {
+		return false
+	}
+
+	return o.Method == "" || labels["method"] == o.Method
+}
+
+func labelsMap(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.GetLabel()))
+
+	for _, label := range m.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+
+	return labels
+}
+
+func isServerError(status string) bool {
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return false
+	}
+
+	// 0 значит, что обработчик ничего не записал в ответ
+	return code == 0 || code >= 500
+}
+
+type WindowSummary struct {
+	Window               string  `json:"window"`
+	Requests             float64 `json:"requests"`
+	Errors               float64 `json:"errors"`
+	SlowRequests         float64 `json:"slow_requests"`
+	AvailabilityBurnRate float64 `json:"availability_burn_rate"`
+	LatencyBurnRate      float64 `json:"latency_burn_rate"`
+}
+
+type ObjectiveSummary struct {
+	Name             string          `json:"name"`
+	Method           string          `json:"method,omitempty"`
+	Route            string          `json:"route"`
+	Availability     float64         `json:"availability"`
+	LatencyThreshold string          `json:"latency_threshold"`
+	LatencyTarget    float64         `json:"latency_target"`
+	Windows          []WindowSummary `json:"windows"`
+}
+
+func (t *Tracker) Summary() []ObjectiveSummary {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	summaries := make([]ObjectiveSummary, 0, len(t.objectives))
+
+	for i, objective := range t.objectives {
+		summary := ObjectiveSummary{
+			Name:             objective.Name,
+			Method:           objective.Method,
+			Route:            objective.Route,
+			Availability:     objective.Availability,
+			LatencyThreshold: objective.LatencyThreshold.String(),
+			LatencyTarget:    objective.LatencyTarget,
+			Windows:          make([]WindowSummary, 0, len(t.windows)),
+		}
+
+		for _, window := range t.windows {
+			delta := t.delta(i, window)
+
+			summary.Windows = append(summary.Windows, WindowSummary{
+				Window:       window.String(),
+				Requests:     delta.Total,
+				Errors:       delta.Errors,
+				SlowRequests: delta.Slow,
+				AvailabilityBurnRate: burnRate(
+					delta.Errors,
+					delta.Total,
+					objective.Availability,
+				),
+				LatencyBurnRate: burnRate(
+					delta.Slow,
+					delta.Total,
+					objective.LatencyTarget,
+				),
+			})
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.burnRateDesc
+}
+
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	for _, summary := range t.Summary() {
+		for _, window := range summary.Windows {
+			ch <- prometheus.MustNewConstMetric(
+				t.burnRateDesc,
+				prometheus.GaugeValue,
+				window.AvailabilityBurnRate,
+				summary.Name, sliAvailability, window.Window,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				t.burnRateDesc,
+				prometheus.GaugeValue,
+				window.LatencyBurnRate,
+				summary.Name, sliLatency, window.Window,
+			)
+		}
+	}
+}
+
+// delta считает прирост счетчиков за окно; если история короче окна,
+// используется самый старый снимок
+func (t *Tracker) delta(objective int, window time.Duration) counts {
+	if len(t.snapshots) == 0 {
+		return counts{}
+	}
+
+	latest := t.snapshots[len(t.snapshots)-1]
+	from := latest.at.Add(-window)
+
+	base := t.snapshots[0]
+
+	for _, s := range t.snapshots {
+		if s.at.After(from) {
+			break
+		}
+
+		base = s
+	}
+
+	delta := latest.counts[objective].sub(base.counts[objective])
+
+	// счетчики сбрасываются только при перезапуске процесса,
+	// но отрицательный прирост все равно не имеет смысла
+	if delta.Total < 0 {
+		return counts{}
+	}
+
+	return delta
+}
+
+// burnRate - во сколько раз доля плохих событий превышает
+// допустимую целью; 1 означает расход бюджета ровно к концу периода
+func burnRate(bad, total, objective float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	budget := 1 - objective
+	if budget <= 0 {
+		return math.Inf(1)
+	}
+
+	return (bad / total) / budget
+}
@@ -0,0 +1,290 @@
+package slo
+
+import (
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeGatherer отдает заданные значения http_requests_total
+// и http_request_duration_seconds для маршрута /api/users
+type fakeGatherer struct {
+	ok, failed uint64
+	// buckets - накопленные счетчики для границ 0.1, 0.25 и 0.5 секунды
+	buckets [3]uint64
+}
+
+func (g *fakeGatherer) Gather() ([]*dto.MetricFamily, error) {
+	request := func(status string, value uint64) *dto.Metric {
+		return &dto.Metric{
+			Label: labels("GET", "/api/users", status),
+			Counter: &dto.Counter{
+				Value: proto.Float64(float64(value)),
+			},
+		}
+	}
+
+	bounds := []float64{0.1, 0.25, 0.5}
+	histogram := &dto.Histogram{
+		SampleCount: proto.Uint64(g.ok + g.failed),
+	}
+
+	for i, bound := range bounds {
+		histogram.Bucket = append(histogram.Bucket, &dto.Bucket{
+			UpperBound:      proto.Float64(bound),
+			CumulativeCount: proto.Uint64(g.buckets[i]),
+		})
+	}
+
+	return []*dto.MetricFamily{
+		{
+			Name: proto.String(requestsMetric),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				request("200", g.ok),
+				request("503", g.failed),
+				// другой маршрут не должен попадать в цель
+				{
+					Label:   labels("GET", "/api/ping", "500"),
+					Counter: &dto.Counter{Value: proto.Float64(1000)},
+				},
+			},
+		},
+		{
+			Name: proto.String(durationMetric),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{{
+				Label:     labels("GET", "/api/users", ""),
+				Histogram: histogram,
+			}},
+		},
+	}, nil
+}
+
+func labels(method, endpoint, status string) []*dto.LabelPair {
+	pairs := []*dto.LabelPair{
+		{Name: proto.String("method"), Value: proto.String(method)},
+		{Name: proto.String("endpoint"), Value: proto.String(endpoint)},
+	}
+
+	if status != "" {
+		pairs = append(pairs, &dto.LabelPair{
+			Name:  proto.String("status"),
+			Value: proto.String(status),
+		})
+	}
+
+	return pairs
+}
+
+func newTestTracker(
+	g prometheus.Gatherer,
+	windows ...time.Duration,
+) *Tracker {
+	return NewTracker(
+		slog.New(slog.DiscardHandler),
+		g,
+		[]Objective{{
+			Name:             "users",
+			Method:           "GET",
+			Route:            "/api/users",
+			Availability:     0.99,
+			LatencyThreshold: 250 * time.Millisecond,
+			LatencyTarget:    0.9,
+		}},
+		windows,
+		time.Minute,
+	)
+}
+
+func TestBurnRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		bad       float64
+		total     float64
+		objective float64
+		want      float64
+	}{
+		{"no traffic", 0, 0, 0.99, 0},
+		{"no bad events", 0, 100, 0.99, 0},
+		{"exactly on budget", 1, 100, 0.99, 1},
+		{"ten times the budget", 10, 100, 0.99, 10},
+		{"everything fails", 100, 100, 0.9, 10},
+		{"no budget", 1, 100, 1, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := burnRate(tt.bad, tt.total, tt.objective)
+
+			if !almostEqual(got, tt.want) {
+				t.Fatalf(
+					"burnRate(%v, %v, %v) = %v, want %v",
+					tt.bad, tt.total, tt.objective, got, tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestTrackerWindowDelta(t *testing.T) {
+	g := &fakeGatherer{}
+	tracker := newTestTracker(g, 5*time.Minute, time.Hour)
+
+	start := time.Now()
+
+	g.ok, g.buckets = 100, [3]uint64{90, 100, 100}
+	mustSample(t, tracker, start.Add(-2*time.Hour))
+
+	g.ok, g.buckets = 1000, [3]uint64{900, 1000, 1000}
+	mustSample(t, tracker, start.Add(-10*time.Minute))
+
+	// за последние 10 минут 100 запросов: 10 ошибок и 20 медленных
+	g.ok, g.failed = 1090, 10
+	g.buckets = [3]uint64{960, 1080, 1100}
+	mustSample(t, tracker, start)
+
+	summary := tracker.Summary()[0]
+
+	short := summary.Windows[0]
+	if short.Requests != 100 || short.Errors != 10 || short.SlowRequests != 20 {
+		t.Fatalf("5m window = %+v", short)
+	}
+
+	if !almostEqual(short.AvailabilityBurnRate, 10) {
+		t.Fatalf(
+			"5m availability burn = %v, want 10",
+			short.AvailabilityBurnRate,
+		)
+	}
+
+	if !almostEqual(short.LatencyBurnRate, 2) {
+		t.Fatalf("5m latency burn = %v, want 2", short.LatencyBurnRate)
+	}
+
+	// история короче часа, поэтому отсчет идет от самого старого снимка
+	long := summary.Windows[1]
+	if long.Requests != 1000 || long.Errors != 10 {
+		t.Fatalf("1h window = %+v", long)
+	}
+}
+
+func TestTrackerZeroTraffic(t *testing.T) {
+	g := &fakeGatherer{ok: 50}
+	tracker := newTestTracker(g, 5*time.Minute)
+
+	now := time.Now()
+
+	mustSample(t, tracker, now.Add(-time.Minute))
+	mustSample(t, tracker, now)
+
+	window := tracker.Summary()[0].Windows[0]
+	if window.Requests != 0 {
+		t.Fatalf("requests without traffic = %v, want 0", window.Requests)
+	}
+
+	if window.AvailabilityBurnRate != 0 || window.LatencyBurnRate != 0 {
+		t.Fatalf("burn rates without traffic = %+v, want 0", window)
+	}
+}
+
+func TestTrackerWithoutSamples(t *testing.T) {
+	tracker := newTestTracker(&fakeGatherer{}, 5*time.Minute)
+
+	window := tracker.Summary()[0].Windows[0]
+	if window != (WindowSummary{Window: "5m0s"}) {
+		t.Fatalf("window without samples = %+v", window)
+	}
+}
+
+func TestTrackerCounterReset(t *testing.T) {
+	g := &fakeGatherer{ok: 1000, failed: 100}
+	tracker := newTestTracker(g, 5*time.Minute)
+
+	now := time.Now()
+
+	mustSample(t, tracker, now.Add(-time.Minute))
+
+	// после перезапуска счетчики начинаются с нуля
+	g.ok, g.failed = 10, 0
+	mustSample(t, tracker, now)
+
+	window := tracker.Summary()[0].Windows[0]
+	if window.Requests != 0 || window.AvailabilityBurnRate != 0 {
+		t.Fatalf("window after counter reset = %+v, want empty", window)
+	}
+}
+
+func TestTrackerPrunesSnapshots(t *testing.T) {
+	g := &fakeGatherer{}
+	tracker := newTestTracker(g, 5*time.Minute)
+
+	start := time.Now()
+
+	for i := range 60 {
+		g.ok = uint64(i * 10)
+		mustSample(t, tracker, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	// окно 5 минут и интервал в минуту: нужны снимки за 6 минут
+	// и один более старый как точка отсчета
+	if n := len(tracker.snapshots); n > 8 {
+		t.Fatalf("tracker keeps %d snapshots, want at most 8", n)
+	}
+
+	window := tracker.Summary()[0].Windows[0]
+	if window.Requests != 50 {
+		t.Fatalf("5m window requests = %v, want 50", window.Requests)
+	}
+}
+
+func TestTrackerCollectsBurnRateMetrics(t *testing.T) {
+	g := &fakeGatherer{}
+	tracker := newTestTracker(g, 5*time.Minute)
+
+	now := time.Now()
+
+	mustSample(t, tracker, now.Add(-time.Minute))
+
+	g.ok, g.failed = 98, 2
+	g.buckets = [3]uint64{100, 100, 100}
+	mustSample(t, tracker, now)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tracker)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	values := map[string]float64{}
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			values[labelsMap(m)["sli"]] = m.GetGauge().GetValue()
+		}
+	}
+
+	if !almostEqual(values[sliAvailability], 2) || values[sliLatency] != 0 {
+		t.Fatalf("burn rate metrics = %v", values)
+	}
+}
+
+func mustSample(t *testing.T, tracker *Tracker, at time.Time) {
+	t.Helper()
+
+	err := tracker.sample(at)
+	if err != nil {
+		t.Fatalf("sample: %v", err)
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return a == b || math.Abs(a-b) < 1e-9
+}
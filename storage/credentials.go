@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const defaultCredentialsRefreshInterval = time.Minute
+
+// ErrDefaultCredentials - MinIO доступен с ключами по умолчанию
+var ErrDefaultCredentials = errors.New(
+	"refusing to use default minioadmin credentials",
+)
+
+type CredentialsConfig struct {
+	AccessKey string
+	SecretKey string
+	// AccessKeyFile и SecretKeyFile - файлы с ключами, например
+	// Docker или Kubernetes secrets; перечитываются раз в RefreshInterval
+	AccessKeyFile   string
+	SecretKeyFile   string
+	RefreshInterval time.Duration
+}
+
+// newCredentials собирает цепочку источников ключей: первым
+// используется тот, что вернул непустые ключи. Порядок - ключи из
+// конфигурации, файлы с ключами, переменные окружения MinIO и AWS,
+// общий файл ~/.aws/credentials и, наконец, IAM роль инстанса.
+func newCredentials(cfg CredentialsConfig) *credentials.Credentials {
+	refresh := cfg.RefreshInterval
+	if refresh <= 0 {
+		refresh = defaultCredentialsRefreshInterval
+	}
+
+	return credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.Static{
+			Value: credentials.Value{
+				AccessKeyID:     cfg.AccessKey,
+				SecretAccessKey: cfg.SecretKey,
+				SignerType:      credentials.SignatureV4,
+			},
+		},
+		&fileKeysProvider{
+			accessKeyFile: cfg.AccessKeyFile,
+			secretKeyFile: cfg.SecretKeyFile,
+			refresh:       refresh,
+		},
+		&credentials.EnvMinio{},
+		&credentials.EnvAWS{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{},
+	})
+}
+
+// checkCredentials не дает запуститься с ключами по умолчанию,
+// которые MinIO создает при первом старте
+func checkCredentials(
+	creds *credentials.Credentials,
+	allowDefault bool,
+) error {
+	value, err := creds.GetWithContext(nil)
+	if err != nil {
+		return fmt.Errorf("retrieving MinIO credentials: %w", err)
+	}
+
+	if value.SignerType.IsAnonymous() {
+		return errors.New("no MinIO credentials found")
+	}
+
+	if !allowDefault &&
+		value.AccessKeyID == "minioadmin" &&
+		value.SecretAccessKey == "minioadmin" {
+		return ErrDefaultCredentials
+	}
+
+	return nil
+}
+
+// fileKeysProvider читает ключи из файлов и считает их устаревшими
+// через refresh, чтобы подхватывать ротацию секретов без перезапуска
+type fileKeysProvider struct {
+	credentials.Expiry
+
+	accessKeyFile string
+	secretKeyFile string
+	refresh       time.Duration
+}
+
+func (p *fileKeysProvider) Retrieve() (credentials.Value, error) {
+	if p.accessKeyFile == "" || p.secretKeyFile == "" {
+		return credentials.Value{
+			SignerType: credentials.SignatureAnonymous,
+		}, nil
+	}
+
+	accessKey, err := readSecretFile(p.accessKeyFile)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	secretKey, err := readSecretFile(p.secretKeyFile)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	p.SetExpiration(time.Now().Add(p.refresh), 0)
+
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+func (p *fileKeysProvider) RetrieveWithCredContext(
+	*credentials.CredContext,
+) (credentials.Value, error) {
+	return p.Retrieve()
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
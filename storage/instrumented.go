@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/dzherb/mifi-go-microservice/metric"
+)
+
+type Store[T any] interface {
+	Set(context.Context, string, T) error
+	Get(context.Context, string) (T, error)
+	GetAll(context.Context) ([]T, error)
+	Count(context.Context) (int, error)
+	Delete(context.Context, string) error
+}
+
+// Instrumented оборачивает любое хранилище и собирает метрики
+// по каждой операции
+type Instrumented[T any] struct {
+	name    string
+	metrics *metric.Metrics
+	next    Store[T]
+}
+
+func NewInstrumented[T any](
+	name string,
+	metrics *metric.Metrics,
+	next Store[T],
+) *Instrumented[T] {
+	return &Instrumented[T]{
+		name:    name,
+		metrics: metrics,
+		next:    next,
+	}
+}
+
+func (s *Instrumented[T]) Set(ctx context.Context, key string, data T) error {
+	start := time.Now()
+	err := s.next.Set(ctx, key, data)
+	s.observe("set", start, err)
+
+	return err
+}
+
+func (s *Instrumented[T]) Get(ctx context.Context, key string) (T, error) {
+	start := time.Now()
+	res, err := s.next.Get(ctx, key)
+	s.observe("get", start, err)
+
+	return res, err
+}
+
+func (s *Instrumented[T]) GetAll(ctx context.Context) ([]T, error) {
+	start := time.Now()
+	res, err := s.next.GetAll(ctx)
+	s.observe("get_all", start, err)
+
+	return res, err
+}
+
+func (s *Instrumented[T]) Count(ctx context.Context) (int, error) {
+	start := time.Now()
+	res, err := s.next.Count(ctx)
+	s.observe("count", start, err)
+
+	return res, err
+}
+
+func (s *Instrumented[T]) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, key)
+	s.observe("delete", start, err)
+
+	return err
+}
+
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+func (s *Instrumented[T]) observe(operation string, start time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeError
+	}
+
+	s.metrics.StorageOperationsTotal.
+		WithLabelValues(s.name, operation, outcome, errorClass(err)).
+		Inc()
+	s.metrics.StorageOperationDuration.
+		WithLabelValues(s.name, operation, outcome).
+		Observe(time.Since(start).Seconds())
+}
+
+func errorClass(err error) string {
+	var minioErr minio.ErrorResponse
+
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, ErrKeyNotFound):
+		return "not_found"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &minioErr):
+		return "s3"
+	default:
+		return "other"
+	}
+}
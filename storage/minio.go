@@ -6,38 +6,74 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
+	"time"
 
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dzherb/mifi-go-microservice/logger"
+	"github.com/dzherb/mifi-go-microservice/metric"
+	"github.com/dzherb/mifi-go-microservice/tracing"
 )
 
 var (
 	ErrKeyNotFound = errors.New("key not found")
 )
 
+var tracer = otel.Tracer("github.com/dzherb/mifi-go-microservice/storage")
+
 type MiniIO[T any] struct {
 	log        *slog.Logger
+	metrics    *metric.Metrics
 	client     *minio.Client
+	transport  *http.Transport
 	bucketName string
 }
 
 type MiniIOConfig struct {
-	Endpoint   string
-	AccessKey  string
-	SecretKey  string
-	BucketName string
-	UseSSL     bool
+	Endpoint    string
+	Credentials CredentialsConfig
+	BucketName  string
+	UseSSL      bool
+	// AllowDefaultCredentials разрешает ключи minioadmin
+	// для локальной разработки
+	AllowDefaultCredentials bool
+	// ExpireAfter задает правило жизненного цикла бакета, по которому
+	// MinIO удаляет объекты старше этого срока; срок округляется
+	// вверх до целых суток, ноль правило не задает
+	ExpireAfter time.Duration
 }
 
 func NewMiniIO[T any](
 	ctx context.Context,
 	log *slog.Logger,
+	metrics *metric.Metrics,
 	cfg MiniIOConfig,
 ) (*MiniIO[T], error) {
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO transport: %w", err)
+	}
+
+	creds := newCredentials(cfg.Credentials)
+
+	err = checkCredentials(creds, cfg.AllowDefaultCredentials)
+	if err != nil {
+		return nil, err
+	}
+
 	minioClient, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Secure: cfg.UseSSL,
+		Creds:     creds,
+		Secure:    cfg.UseSSL,
+		Transport: otelhttp.NewTransport(transport),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
@@ -45,7 +81,9 @@ func NewMiniIO[T any](
 
 	storage := &MiniIO[T]{
 		log:        log,
+		metrics:    metrics,
 		client:     minioClient,
+		transport:  transport,
 		bucketName: cfg.BucketName,
 	}
 
@@ -68,13 +106,67 @@ func NewMiniIO[T any](
 		log.Info("bucket created", slog.String("bucket_name", cfg.BucketName))
 	}
 
+	if cfg.ExpireAfter > 0 {
+		err = minioClient.SetBucketLifecycle(
+			ctx,
+			cfg.BucketName,
+			expirationLifecycle(cfg.ExpireAfter),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set bucket lifecycle: %w", err)
+		}
+	}
+
 	return storage, nil
 }
 
-func (s *MiniIO[T]) Set(ctx context.Context, key string, data T) error {
+func expirationLifecycle(after time.Duration) *lifecycle.Configuration {
+	days := max(int(math.Ceil(after.Hours()/24)), 1)
+
+	cfg := lifecycle.NewConfiguration()
+	cfg.Rules = []lifecycle.Rule{{
+		ID:     "expire-objects",
+		Status: "Enabled",
+		Expiration: lifecycle.Expiration{
+			Days: lifecycle.ExpirationDays(days),
+		},
+	}}
+
+	return cfg
+}
+
+// Ping проверяет, что бакет доступен
+func (s *MiniIO[T]) Ping(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.Ping")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucketName)
+	}
+
+	return nil
+}
+
+// Close закрывает простаивающие соединения с MinIO; клиент
+// не держит других ресурсов
+func (s *MiniIO[T]) Close(context.Context) error {
+	s.transport.CloseIdleConnections()
+
+	return nil
+}
+
+func (s *MiniIO[T]) Set(ctx context.Context, key string, data T) (err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.Set")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	dataSerialized, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("marshaling data for key %s: %w", key, err)
+		return fmt.Errorf("marshaling data: %w", err)
 	}
 
 	reader := bytes.NewReader(dataSerialized)
@@ -90,24 +182,46 @@ func (s *MiniIO[T]) Set(ctx context.Context, key string, data T) error {
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("saving data to s3 with key %s: %w", key, err)
+		return fmt.Errorf("saving data to s3: %w", err)
 	}
 
-	s.log.Info(
+	s.metrics.StorageObjectSize.
+		WithLabelValues(s.bucketName, "set").
+		Observe(float64(len(dataSerialized)))
+
+	logger.FromContext(ctx, s.log).InfoContext(
+		ctx,
 		"data saved to s3",
 		slog.String("bucket_name", s.bucketName),
-		slog.String("key", key),
+		slog.String("object_key", key),
 	)
 
 	return nil
 }
 
-func (s *MiniIO[T]) GetAll(ctx context.Context) ([]T, error) {
+func (s *MiniIO[T]) GetAll(ctx context.Context) (_ []T, err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.GetAll")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	objCh := s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{})
 
 	objects := make([]T, 0)
 
+	scanned := 0
+
+	defer func() {
+		s.metrics.StorageListedObjectsTotal.
+			WithLabelValues(s.bucketName).
+			Add(float64(scanned))
+	}()
+
 	for objInfo := range objCh {
+		if objInfo.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", objInfo.Err)
+		}
+
+		scanned++
+
 		obj, err := s.Get(ctx, objInfo.Key)
 		if err != nil {
 			return nil, fmt.Errorf(
@@ -120,10 +234,44 @@ func (s *MiniIO[T]) GetAll(ctx context.Context) ([]T, error) {
 		objects = append(objects, obj)
 	}
 
+	span.SetAttributes(attribute.Int("s3.objects_count", len(objects)))
+
 	return objects, nil
 }
 
-func (s *MiniIO[T]) Get(ctx context.Context, key string) (T, error) {
+func (s *MiniIO[T]) Count(ctx context.Context) (_ int, err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.Count")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	count := 0
+
+	defer func() {
+		s.metrics.StorageListedObjectsTotal.
+			WithLabelValues(s.bucketName).
+			Add(float64(count))
+	}()
+
+	for objInfo := range s.client.ListObjects(
+		ctx,
+		s.bucketName,
+		minio.ListObjectsOptions{},
+	) {
+		if objInfo.Err != nil {
+			return 0, fmt.Errorf("failed to list objects: %w", objInfo.Err)
+		}
+
+		count++
+	}
+
+	span.SetAttributes(attribute.Int("s3.objects_count", count))
+
+	return count, nil
+}
+
+func (s *MiniIO[T]) Get(ctx context.Context, key string) (_ T, err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.Get")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	var res T
 
 	object, err := s.client.GetObject(
@@ -139,11 +287,17 @@ func (s *MiniIO[T]) Get(ctx context.Context, key string) (T, error) {
 	defer func() {
 		err := object.Close()
 		if err != nil {
-			s.log.Error("failed to close s3 object", slog.String("key", key))
+			logger.FromContext(ctx, s.log).ErrorContext(
+				ctx,
+				"failed to close s3 object",
+				slog.String("object_key", key),
+			)
 		}
 	}()
 
-	err = json.NewDecoder(object).Decode(&res)
+	counter := &countingReader{r: object}
+
+	err = json.NewDecoder(counter).Decode(&res)
 	if err != nil {
 		var minioErr minio.ErrorResponse
 
@@ -154,11 +308,18 @@ func (s *MiniIO[T]) Get(ctx context.Context, key string) (T, error) {
 		return res, fmt.Errorf("failed to unmarshal s3 object: %w", err)
 	}
 
+	s.metrics.StorageObjectSize.
+		WithLabelValues(s.bucketName, "get").
+		Observe(float64(counter.n))
+
 	return res, nil
 }
 
-func (s *MiniIO[T]) Delete(ctx context.Context, key string) error {
-	err := s.client.RemoveObject(
+func (s *MiniIO[T]) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := s.startSpan(ctx, "MiniIO.Delete")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	err = s.client.RemoveObject(
 		ctx,
 		s.bucketName,
 		key,
@@ -168,5 +329,38 @@ func (s *MiniIO[T]) Delete(ctx context.Context, key string) error {
 		return fmt.Errorf("failed to remove s3 object: %w", err)
 	}
 
+	logger.FromContext(ctx, s.log).InfoContext(
+		ctx,
+		"data removed from s3",
+		slog.String("bucket_name", s.bucketName),
+		slog.String("object_key", key),
+	)
+
 	return nil
 }
+
+// startSpan не записывает ключ объекта: в логах он хешируется
+// как object_key, и трассы не должны раскрывать его в открытом виде
+func (s *MiniIO[T]) startSpan(
+	ctx context.Context,
+	name string,
+) (context.Context, trace.Span) {
+	return tracer.Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("s3.bucket", s.bucketName)),
+	)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}